@@ -0,0 +1,118 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// the separator between the name of an archive and the path of one of its members, archive.zip!dir/file.txt
+const memberSeparator = "!"
+
+// the kind of archive of a file for --search-zip, by its extension: zip, tar or tar.gz. Empty if it isn't one
+func archiveKind(filename string) string {
+	name := strings.ToLower(filename)
+	switch {
+	case strings.HasSuffix(name, ".zip"), strings.HasSuffix(name, ".jar"):
+		return "zip"
+	case strings.HasSuffix(name, ".tar"):
+		return "tar"
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return "tar.gz"
+	}
+	return ""
+}
+
+func isArchive(filename string) bool {
+	return archiveKind(filename) != ""
+}
+
+// search each regular file of an archive as its own input, named archive!member.
+// The --include and --exclude globs apply to the paths of the members
+func searchArchive(ctx context.Context, re matcher, filename, kind string, p *printer) (bool, error) {
+	if kind == "zip" {
+		return searchZip(ctx, re, filename, p)
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	var input io.Reader = f
+	if kind == "tar.gz" {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", filename, err)
+		}
+		input = gz
+	}
+	return searchTar(ctx, re, filename, input, p)
+}
+
+func searchZip(ctx context.Context, re matcher, filename string, p *printer) (bool, error) {
+	r, err := zip.OpenReader(filename)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", filename, err)
+	}
+	defer r.Close()
+	selected := false
+	var errs []error
+	for _, member := range r.File {
+		if ctx.Err() != nil || selected && p.opts.quiet {
+			break
+		}
+		if member.FileInfo().IsDir() || !p.opts.isSelectedFile(member.Name) {
+			continue
+		}
+		member_selected, err := searchMember(ctx, re, filename+memberSeparator+member.Name, member.Open, p)
+		selected = selected || member_selected
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return selected, errors.Join(errs...)
+}
+
+func searchTar(ctx context.Context, re matcher, filename string, input io.Reader, p *printer) (bool, error) {
+	r := tar.NewReader(input)
+	selected := false
+	var errs []error
+	for ctx.Err() == nil && !(selected && p.opts.quiet) {
+		hdr, err := r.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			//the rest of the archive can't be read
+			errs = append(errs, fmt.Errorf("%s: %w", filename, err))
+			break
+		}
+		if hdr.Typeflag != tar.TypeReg || !p.opts.isSelectedFile(hdr.Name) {
+			continue
+		}
+		open := func() (io.ReadCloser, error) {
+			return io.NopCloser(r), nil
+		}
+		member_selected, err := searchMember(ctx, re, filename+memberSeparator+hdr.Name, open, p)
+		selected = selected || member_selected
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return selected, errors.Join(errs...)
+}
+
+// search a member of an archive, open gives the content of the member
+func searchMember(ctx context.Context, re matcher, name string, open func() (io.ReadCloser, error), p *printer) (bool, error) {
+	input, err := open()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", name, err)
+	}
+	defer input.Close()
+	return searchInput(ctx, re, name, input, p)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+)
+
+// the size of the first buffer of a file, looked at to tell a binary file from a text file
+const binaryPeekSize = 32 * 1024
+
+// like GNU grep, a file is binary when its first buffer contains a NUL byte
+func isBinary(head []byte) bool {
+	return bytes.IndexByte(head, 0) >= 0
+}
+
+// read the first buffer of an input, returns it along with a reader yielding the whole input again.
+// A single read is enough, so that a pipe isn't waited for until the buffer is full
+func readHead(input io.Reader) ([]byte, io.Reader) {
+	head := make([]byte, binaryPeekSize)
+	n, _ := input.Read(head)
+	head = head[:n]
+	return head, io.MultiReader(bytes.NewReader(head), input)
+}
+
+// search a binary file, its lines are not printed but a message telling that the file has a match.
+// Returns true if a line was selected along with the error that stopped the reading of the input
+func grepBinary(ctx context.Context, re matcher, filename string, lines lineSource, p *printer) (bool, error) {
+	if p.opts.max_count == 0 {
+		return false, nil
+	}
+	for lines.Scan() {
+		if ctx.Err() != nil {
+			break
+		}
+		if findFirstMatch(re, lines, lines.Line()) != nil {
+			//with --json the end message of the file gives the offset of its binary data
+			if p.opts.json {
+				p.printBegin(filename)
+				return true, nil
+			} else if p.opts.format == "sarif" { //a binary file has no location to report
+				return true, nil
+			}
+			p.out.WriteString("Binary file " + filename + " matches\n")
+			p.printed = true
+			return true, nil
+		}
+	}
+	return false, lines.Err()
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/codecrafters-io/grep-starter-go/internal/term"
+)
+
+// the SGR sequences used to color the output, they can be changed with the GREP_COLORS environment variable
+type colors struct {
+	selected_match string //ms matched text in a selected line
+	context_match  string //mc matched text in a context line
+	selected_line  string //sl the rest of a selected line
+	context_line   string //cx the rest of a context line
+	filename       string //fn
+	line_number    string //ln line and column numbers
+	byte_offset    string //bn
+	separator      string //se separators between the prefix fields, and between groups of lines
+	no_erase       bool   //ne don't erase to the end of the line after each colored part
+}
+
+// the colors used by GNU grep when GREP_COLORS is not set
+var defaultColors = colors{
+	selected_match: "01;31",
+	context_match:  "01;31",
+	filename:       "35",
+	line_number:    "32",
+	byte_offset:    "32",
+	separator:      "36",
+}
+
+// the colors of an output that is not colored
+var noColors colors
+
+// parse the capabilities of GREP_COLORS on top of the default colors, for instance "ms=01;32:fn=34:ne".
+// unknown capabilities are ignored, like grep does
+func parseGrepColors(env string) colors {
+	c := defaultColors
+	for _, capability := range strings.Split(env, ":") {
+		name, value, _ := strings.Cut(capability, "=")
+		switch name {
+		case "mt":
+			c.selected_match, c.context_match = value, value
+		case "ms":
+			c.selected_match = value
+		case "mc":
+			c.context_match = value
+		case "sl":
+			c.selected_line = value
+		case "cx":
+			c.context_line = value
+		case "fn":
+			c.filename = value
+		case "ln":
+			c.line_number = value
+		case "bn":
+			c.byte_offset = value
+		case "se":
+			c.separator = value
+		case "ne":
+			c.no_erase = true
+		}
+	}
+	return c
+}
+
+// the sequence starting a colored part
+func (c *colors) start(sgr string) string {
+	if c.no_erase {
+		return "\033[" + sgr + "m"
+	}
+	return "\033[" + sgr + "m\033[K"
+}
+
+// the sequence ending a colored part
+func (c *colors) end() string {
+	if c.no_erase {
+		return "\033[m"
+	}
+	return "\033[m\033[K"
+}
+
+// decide if the output is colored from the --color option: always, never, or auto when stdout is a terminal
+func useColors(when string) bool {
+	switch when {
+	case "always":
+		return true
+	case "auto":
+		return os.Getenv("TERM") != "dumb" && term.IsTerminal(os.Stdout)
+	}
+	return false
+}
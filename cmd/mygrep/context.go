@@ -0,0 +1,93 @@
+package main
+
+// the separator between the prefix fields and the text of a context line
+const contextSeparator = '-'
+
+// a line that may be printed as context
+type contextLine struct {
+	line_number, offset int
+	text                []byte
+}
+
+// ring buffer keeping the last lines that were not printed, to print them as before context
+type contextBuffer struct {
+	lines       []contextLine
+	start, size int
+}
+
+func newContextBuffer(capacity int) *contextBuffer {
+	return &contextBuffer{lines: make([]contextLine, capacity)}
+}
+
+// add a line to the buffer, the oldest line is dropped when the buffer is full.
+// The text is copied since the scanner reuses its buffer for the next lines
+func (cb *contextBuffer) push(cl contextLine) {
+	if len(cb.lines) == 0 {
+		return
+	}
+	x := cb.start
+	if cb.size < len(cb.lines) {
+		x = (cb.start + cb.size) % len(cb.lines)
+		cb.size++
+	} else {
+		cb.start = (cb.start + 1) % len(cb.lines)
+	}
+	cl.text = append(cb.lines[x].text[:0], cl.text...)
+	cb.lines[x] = cl
+}
+
+// remove all the lines from the buffer, the oldest line comes first
+func (cb *contextBuffer) drain() []contextLine {
+	lines := make([]contextLine, cb.size)
+	for x := range lines {
+		lines[x] = cb.lines[(cb.start+x)%len(cb.lines)]
+	}
+	cb.start, cb.size = 0, 0
+	return lines
+}
+
+// print the lines around the selected lines of a file, for the -A -B -C options.
+// groups of lines that are not adjacent are separated by the group separator, overlapping contexts are merged
+type contextHandler struct {
+	p               *printer
+	filename        string
+	before          *contextBuffer
+	after_remaining int //number of lines left to print after the last selected line
+	last_printed    int //line number of the last printed line, 0 if nothing was printed yet
+}
+
+func newContextHandler(p *printer, filename string) *contextHandler {
+	return &contextHandler{p: p, filename: filename, before: newContextBuffer(p.opts.before_context)}
+}
+
+// to call before printing a selected line, prints the group separator and the before context
+func (ch *contextHandler) beforeMatch(line_number int) {
+	lines := ch.before.drain()
+	first := line_number
+	if len(lines) > 0 {
+		first = lines[0].line_number
+	}
+	if ch.p.printed && ch.p.opts.hasContext() && (ch.last_printed == 0 || first > ch.last_printed+1) {
+		ch.p.printGroupSeparator()
+	}
+	for _, cl := range lines {
+		ch.p.printLine(outputLine{filename: ch.filename, line_number: cl.line_number, offset: cl.offset, text: cl.text}, contextSeparator)
+	}
+}
+
+// to call after printing a selected line
+func (ch *contextHandler) afterMatch(line_number int) {
+	ch.last_printed = line_number
+	ch.after_remaining = ch.p.opts.after_context
+}
+
+// to call with the lines that are not selected, they are printed as after context or kept for the before context
+func (ch *contextHandler) notSelected(cl contextLine) {
+	if ch.after_remaining > 0 {
+		ch.after_remaining--
+		ch.p.printLine(outputLine{filename: ch.filename, line_number: cl.line_number, offset: cl.offset, text: cl.text}, contextSeparator)
+		ch.last_printed = cl.line_number
+		return
+	}
+	ch.before.push(cl)
+}
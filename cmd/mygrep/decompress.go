@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// a compression format of --search-zip, recognized by the extension of a file or by its first bytes
+type compression struct {
+	extensions []string
+	magic      [][]byte
+	reader     func(r io.Reader) (io.Reader, error)
+}
+
+var compressions = []compression{
+	{
+		extensions: []string{".gz"},
+		magic:      [][]byte{{0x1f, 0x8b}},
+		reader: func(r io.Reader) (io.Reader, error) {
+			return gzip.NewReader(r)
+		},
+	},
+	{
+		//BZh, the block size from 1 to 9, then the magic of the first block
+		extensions: []string{".bz2"},
+		magic:      bzip2Magic(),
+		reader: func(r io.Reader) (io.Reader, error) {
+			return bzip2.NewReader(r), nil
+		},
+	},
+	{
+		//the zlib header is only two bytes, only the usual compression levels are recognized without the extension
+		extensions: []string{".zlib", ".zz"},
+		magic:      [][]byte{{0x78, 0x01}, {0x78, 0x9c}, {0x78, 0xda}},
+		reader: func(r io.Reader) (io.Reader, error) {
+			return zlib.NewReader(r)
+		},
+	},
+}
+
+// the magic numbers of a bzip2 stream, one for each block size
+func bzip2Magic() [][]byte {
+	magic := make([][]byte, 0, 9)
+	for size := '1'; size <= '9'; size++ {
+		magic = append(magic, []byte("BZh"+string(size)+"1AY&SY"))
+	}
+	return magic
+}
+
+// wrap the input of a file in a decompressor when the file is compressed, returns the input to read
+// along with true if it is decompressed. Line numbers and byte offsets then refer to the decompressed content.
+// A file recognized by its first bytes rather than by its extension is searched as text when it can't be decompressed
+func decompress(filename string, input io.Reader) (io.Reader, bool, error) {
+	br := bufio.NewReader(input)
+	head, _ := br.Peek(10)
+	ext := strings.ToLower(filepath.Ext(filename))
+	for _, c := range compressions {
+		by_extension := slices.Contains(c.extensions, ext)
+		if !by_extension && !hasMagic(head, c.magic) {
+			continue
+		}
+		//the start of the stream is decompressed right away to check it, what was read is kept to read it again
+		rr := &replayReader{r: br}
+		r, err := c.reader(rr)
+		if err == nil {
+			dr := bufio.NewReader(r)
+			if _, err = dr.Peek(1); err == io.EOF {
+				err = nil
+			}
+			r = dr
+		}
+		if err != nil && !by_extension {
+			return io.MultiReader(bytes.NewReader(rr.read), br), false, nil
+		} else if err != nil {
+			return nil, false, err
+		}
+		rr.stop()
+		return r, true, nil
+	}
+	return br, false, nil
+}
+
+// a reader keeping the bytes read through it until stop is called
+type replayReader struct {
+	r       io.Reader
+	read    []byte
+	stopped bool
+}
+
+func (rr *replayReader) Read(b []byte) (int, error) {
+	n, err := rr.r.Read(b)
+	if !rr.stopped {
+		rr.read = append(rr.read, b[:n]...)
+	}
+	return n, err
+}
+
+func (rr *replayReader) stop() {
+	rr.read, rr.stopped = nil, true
+}
+
+func hasMagic(head []byte, magic [][]byte) bool {
+	for _, m := range magic {
+		if bytes.HasPrefix(head, m) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/codecrafters-io/grep-starter-go/regex"
+)
+
+// print the syntax tree of each pattern for --explain: an indented tree, or a JSON object per line with --explain=json.
+// A *regex.SyntaxError is returned for the first invalid pattern
+func explainPatterns(opts *options, out io.Writer) error {
+	for _, pattern := range opts.patterns {
+		root, err := regex.Explain(pattern)
+		if err != nil {
+			return err
+		}
+		if opts.explain == "json" {
+			b, err := json.Marshal(root)
+			if err != nil {
+				//the tree only holds strings and numbers
+				panic(err)
+			}
+			fmt.Fprintf(out, "%s\n", b)
+		} else {
+			printNode(out, root, 0)
+		}
+	}
+	return nil
+}
+
+// print a node and its children, a line each, the children indented under their parent:
+//
+//	group `(?<user>\w+)` 1-13 group=1 name=user
+//	  word `\w+` 9-12 quantifier=+
+func printNode(out io.Writer, node *regex.Node, depth int) {
+	fmt.Fprintf(out, "%s%s `%s` %d-%d", strings.Repeat("  ", depth), node.Kind, node.Text, node.Start, node.End)
+	if node.Group > 0 {
+		fmt.Fprintf(out, " group=%d", node.Group)
+	}
+	if node.Name != "" {
+		fmt.Fprintf(out, " name=%s", node.Name)
+	}
+	if node.Quantifier != "" {
+		fmt.Fprintf(out, " quantifier=%s", node.Quantifier)
+	}
+	fmt.Fprintln(out)
+	for _, child := range node.Children {
+		printNode(out, child, depth+1)
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+	"unicode/utf8"
+)
+
+// the messages of --json, one JSON object per line in the schema of ripgrep's --json output:
+// begin and end around the lines of each input with a match, match and context for the lines, then a summary
+type jsonMessage struct {
+	Type string `json:"type"`
+	Data any    `json:"data"`
+}
+
+type jsonBegin struct {
+	Path jsonData `json:"path"`
+}
+
+// the data of a match or context message
+type jsonLine struct {
+	Path           jsonData       `json:"path"`
+	Lines          jsonData       `json:"lines"`
+	LineNumber     int            `json:"line_number"`
+	AbsoluteOffset int            `json:"absolute_offset"`
+	Submatches     []jsonSubmatch `json:"submatches"`
+}
+
+type jsonSubmatch struct {
+	Match jsonData `json:"match"`
+	Start int      `json:"start"`
+	End   int      `json:"end"`
+}
+
+type jsonEnd struct {
+	Path         jsonData    `json:"path"`
+	BinaryOffset *int        `json:"binary_offset"`
+	Stats        searchStats `json:"stats"`
+}
+
+type jsonSummary struct {
+	ElapsedTotal jsonDuration `json:"elapsed_total"`
+	Stats        searchStats  `json:"stats"`
+}
+
+// a string of the output: {"text": ...} when it is valid UTF-8, {"bytes": ...} in base64 otherwise
+type jsonData map[string]string
+
+func newJSONData(b []byte) jsonData {
+	if utf8.Valid(b) {
+		return jsonData{"text": string(b)}
+	}
+	return jsonData{"bytes": base64.StdEncoding.EncodeToString(b)}
+}
+
+type jsonDuration struct {
+	Secs  int64  `json:"secs"`
+	Nanos int    `json:"nanos"`
+	Human string `json:"human"`
+}
+
+func newJSONDuration(d time.Duration) jsonDuration {
+	return jsonDuration{Secs: int64(d / time.Second), Nanos: int(d % time.Second), Human: fmt.Sprintf("%.6fs", d.Seconds())}
+}
+
+// the statistics of the search of an input, or of all of them for the summary
+type searchStats struct {
+	Elapsed           jsonDuration `json:"elapsed"`
+	Searches          int          `json:"searches"`
+	SearchesWithMatch int          `json:"searches_with_match"`
+	BytesSearched     int64        `json:"bytes_searched"`
+	BytesPrinted      int64        `json:"bytes_printed"`
+	MatchedLines      int          `json:"matched_lines"`
+	Matches           int          `json:"matches"`
+	elapsed           time.Duration
+}
+
+// add the statistics of another search
+func (s *searchStats) add(other searchStats) {
+	s.elapsed += other.elapsed
+	s.Elapsed = newJSONDuration(s.elapsed)
+	s.Searches += other.Searches
+	s.SearchesWithMatch += other.SearchesWithMatch
+	s.BytesSearched += other.BytesSearched
+	s.BytesPrinted += other.BytesPrinted
+	s.MatchedLines += other.MatchedLines
+	s.Matches += other.Matches
+}
+
+// a reader counting the bytes read through it, for the bytes searched of the statistics
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(b []byte) (int, error) {
+	n, err := cr.r.Read(b)
+	cr.n += int64(n)
+	return n, err
+}
+
+// print a message on its own line, returns the number of bytes printed
+func (p *printer) printJSON(typ string, data any) int64 {
+	b, err := json.Marshal(jsonMessage{Type: typ, Data: data})
+	if err != nil {
+		//the messages only hold strings and numbers
+		panic(err)
+	}
+	p.out.Write(b)
+	p.out.WriteByte('\n')
+	return int64(len(b) + 1)
+}
+
+// print a selected line as a match message or a context line as a context message, after the begin message
+// of the input if this is its first line
+func (p *printer) printJSONLine(ol outputLine, sep byte) {
+	p.printBegin(ol.filename)
+	line := jsonLine{Path: newJSONData([]byte(ol.filename)), LineNumber: ol.line_number, AbsoluteOffset: ol.offset, Submatches: []jsonSubmatch{}}
+	//like ripgrep, the lines keep their terminator
+	line.Lines = newJSONData(append(ol.text[:len(ol.text):len(ol.text)], p.opts.terminator()))
+	typ := "context"
+	if sep == matchSeparator {
+		typ = "match"
+		for _, match := range ol.matches {
+			line.Submatches = append(line.Submatches, jsonSubmatch{Match: newJSONData(ol.text[match[0]:match[1]]), Start: match[0], End: match[1]})
+		}
+		p.stats.MatchedLines++
+		p.stats.Matches += len(ol.matches)
+	}
+	p.stats.BytesPrinted += p.printJSON(typ, line)
+}
+
+// print the begin message of the input, unless it was already printed
+func (p *printer) printBegin(filename string) {
+	if !p.begun {
+		p.begun = true
+		p.stats.BytesPrinted += p.printJSON("begin", jsonBegin{Path: newJSONData([]byte(filename))})
+	}
+}
+
+// to call before searching an input, its statistics start over
+func (p *printer) beginInput() {
+	p.begun, p.stats = false, searchStats{}
+}
+
+// to call once an input was searched, prints the end message of an input that printed a begin message
+// and adds the statistics of the input to the total of the printer. binary_offset is the offset of the first NUL
+// byte of a binary file, -1 for a text file
+func (p *printer) endInput(filename string, selected bool, bytes_searched int64, elapsed time.Duration, binary_offset int) {
+	p.stats.Searches = 1
+	if selected {
+		p.stats.SearchesWithMatch = 1
+	}
+	p.stats.BytesSearched = bytes_searched
+	p.stats.elapsed, p.stats.Elapsed = elapsed, newJSONDuration(elapsed)
+	if p.begun {
+		end := jsonEnd{Path: newJSONData([]byte(filename)), Stats: p.stats}
+		if binary_offset >= 0 {
+			end.BinaryOffset = &binary_offset
+		}
+		p.printJSON("end", end)
+	}
+	p.total.add(p.stats)
+}
@@ -1,41 +1,168 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+
+	"github.com/codecrafters-io/grep-starter-go/regex"
 )
 
-// Usage: echo <input_text> | your_program.sh -E <pattern>
+// the name used for stdin in the output
+const stdinName = "(standard input)"
+
+// Usage: echo <input_text> | your_program.sh -E [-onbHhwxqsaIruzZU] [-e PATTERN] [-f FILE] [-m NUM] [--column] [-A|-B|-C NUM] [--color[=WHEN]] [--replace=TEMPLATE [--in-place[=SUFFIX]] | --split] [--[no-]mmap] [--binary-files=TYPE] [--no-ignore] [--hidden] [--include|--exclude=GLOB] [--null-data] [--json | --format=sarif|emacs|TEMPLATE | --vimgrep] [--column-unit=UNIT] [--explain[=FORMAT]] <pattern> [file...]
 func main() {
-	if len(os.Args) < 3 || os.Args[1] != "-E" {
-		fmt.Fprintf(os.Stderr, "usage: mygrep -E <pattern>\n")
+	opts, err := parseArgs(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		fmt.Fprintf(os.Stderr, "usage: mygrep -E [-onbHhwxqsaIruzZU] [-e PATTERN] [-f FILE] [-m NUM] [--column] [-A|-B|-C NUM] [--color[=WHEN]] [--replace=TEMPLATE [--in-place[=SUFFIX]] | --split] [--[no-]mmap] [--binary-files=TYPE] [--no-ignore] [--hidden] [--include|--exclude=GLOB] [--null-data] [--json | --format=sarif|emacs|TEMPLATE | --vimgrep] [--column-unit=UNIT] [--explain[=FORMAT]] <pattern> [file...]\n")
 		os.Exit(2) // 1 means no lines were selected, >1 means error
 	}
 
-	pattern := os.Args[2]
+	//--explain prints the patterns rather than searching with them
+	if opts.explain != "" {
+		out := bufio.NewWriter(os.Stdout)
+		err := explainPatterns(opts, out)
+		out.Flush()
+		if err != nil {
+			exitWithPatternError(err)
+		}
+		os.Exit(0)
+	}
 
-	line, err := io.ReadAll(os.Stdin) // assume we're only dealing with a single line
+	re, err := compilePattern(opts)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: read input text: %v\n", err)
-		os.Exit(2)
+		exitWithPatternError(err)
 	}
 
-	gh := newGrepHandler(line, pattern)
-	if !gh.ExtractPatterns() {
-		fmt.Fprintf(os.Stderr, "unsupported pattern: \"%s\"", pattern)
-		os.Exit(2)
+	var colors *colors
+	//the formats meant for other programs are never colored
+	if useColors(opts.color) && !opts.json && (opts.format == "" || opts.format == "emacs") {
+		c := parseGrepColors(os.Getenv("GREP_COLORS"))
+		colors = &c
 	}
-	ok, err := gh.matchPatterns()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(2)
+	if len(opts.files) == 0 {
+		opts.files = []string{"-"}
 	}
+	out := bufio.NewWriter(os.Stdout)
+	failed := false
+	selected := searchFiles(opts, re, colors, out, func(err error) {
+		failed = true
+		if !opts.no_messages {
+			out.Flush()
+			fmt.Fprintf(os.Stderr, "mygrep: %v\n", err)
+		}
+	})
+	out.Flush()
 
-	if !ok {
+	//like grep, -q exits with success on a selected line even if an error happened
+	if opts.quiet && selected {
+		os.Exit(0)
+	} else if failed {
+		os.Exit(2)
+	} else if !selected {
 		os.Exit(1)
 	}
 
 	// default exit code is 0 which means success
 	os.Exit(0)
 }
+
+// report an invalid pattern, with a diagram of the offending part for a syntax error, and exit
+func exitWithPatternError(err error) {
+	var syntax_err *regex.SyntaxError
+	if errors.As(err, &syntax_err) {
+		fmt.Fprintf(os.Stderr, "mygrep: invalid pattern: %s at offset %d\n%s\n", syntax_err.Code, syntax_err.Offset, syntax_err.Diagram())
+	} else {
+		fmt.Fprintf(os.Stderr, "mygrep: %v\n", err)
+	}
+	os.Exit(2)
+}
+
+// open a file to read its content, - is stdin
+func openInput(filename string) (io.ReadCloser, error) {
+	if filename == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(filename)
+}
+
+// match the pattern against every line of the input and print the selected lines, the lines are read
+// in chunks or found in a memory mapped file so that the size of the input doesn't matter.
+// Returns true if at least one line was selected along with the error that stopped the reading of the input.
+// The search stops early when ctx is cancelled
+func grepLines(ctx context.Context, re matcher, filename string, lines lineSource, p *printer) (bool, error) {
+	selected := false
+	selected_count := 0
+	ch := newContextHandler(p, filename)
+	for lines.Scan() {
+		if ctx.Err() != nil {
+			break
+		}
+		line := lines.Line()
+		line_number := lines.LineNumber()
+		offset := int(lines.Offset()) //byte offset of the line in the input
+		//-m after the last selected line, the following lines are only printed as its after context
+		if p.opts.max_count >= 0 && selected_count >= p.opts.max_count {
+			if ch.after_remaining == 0 {
+				break
+			}
+			ch.notSelected(contextLine{line_number: line_number, offset: offset, text: line})
+			continue
+		}
+		if p.opts.only_matching { //context lines are not printed with -o
+			matches := findAllMatches(re, lines, line)
+			for _, match := range matches {
+				//like GNU grep, an empty match selects the line but isn't printed
+				if match[0] == match[1] {
+					continue
+				}
+				text := line[match[0]:match[1]]
+				if p.opts.has_replace {
+					text = re.Expand(nil, p.opts.replace, line, match)
+				}
+				p.printLine(outputLine{filename: filename, line_number: line_number, column: p.columnAt(line, match[0]), offset: offset + match[0], text: text, matches: [][]int{{0, len(text)}}}, matchSeparator)
+			}
+			if len(matches) > 0 {
+				selected = true
+				selected_count++
+			}
+		} else {
+			if loc := findFirstMatch(re, lines, line); loc != nil {
+				text, matches := line, [][]int{loc}
+				if p.opts.has_replace {
+					text, matches = replaceMatches(re, line, findAllMatches(re, lines, line), p.opts.replace)
+				} else if p.colors != nil || p.opts.json || p.opts.perMatch() { //every match of the line is highlighted, or reported
+					matches = findAllMatches(re, lines, line)
+				}
+				ch.beforeMatch(line_number)
+				if p.opts.split {
+					for _, field := range re.Split(string(line), -1) {
+						p.printLine(outputLine{filename: filename, line_number: line_number, column: p.columnAt(line, loc[0]), offset: offset, text: []byte(field)}, matchSeparator)
+					}
+				} else {
+					p.printLine(outputLine{filename: filename, line_number: line_number, column: p.columnAt(line, loc[0]), offset: offset, text: text, matches: matches}, matchSeparator)
+				}
+				last_number := line_number
+				if p.opts.multiline { //the line is made of all the lines touched by the matches
+					last_number += bytes.Count(line, []byte{'\n'})
+				}
+				ch.afterMatch(last_number)
+				selected = true
+				selected_count++
+			} else {
+				ch.notSelected(contextLine{line_number: line_number, offset: offset, text: line})
+			}
+		}
+		//-q the first selected line is enough
+		if selected && p.opts.quiet {
+			break
+		}
+	}
+	return selected, lines.Err()
+}
@@ -1,408 +1,852 @@
 package main
 
 import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/codecrafters-io/grep-starter-go/regex"
 )
 
-var testSimplePatternExtraction = []struct {
+// a search of a single input, named file, and the output expected from it
+type outputTest struct {
 	description string
-	pattern     string
-	expected    []string
-}{
-	{
-		description: "single character",
-		pattern:     "d",
-		expected:    []string{"d"},
-	},
-	{
-		description: "single character",
-		pattern:     "w",
-		expected:    []string{"w"},
-	},
-	{
-		description: "single character",
-		pattern:     "q",
-		expected:    []string{"q"},
-	},
-	{
-		description: "multiple character classes",
-		pattern:     "\\d\\w",
-		expected:    []string{"\\d", "\\w"},
-	},
-	{
-		description: "character classe and single char",
-		pattern:     "\\dw",
-		expected:    []string{"\\d", "w"},
-	},
-	{
-		description: "two character classes and single char",
-		pattern:     "\\d\\ww",
-		expected:    []string{"\\d", "\\w", "w"},
-	},
-	{
-		description: "two character classes and single char",
-		pattern:     "\\d \\ww",
-		expected:    []string{"\\d", " ", "\\w", "w"},
-	},
-	{
-		description: "character group",
-		pattern:     "[abc]",
-		expected:    []string{"[abc]"},
-	},
-	{
-		description: "end of string anchor",
-		pattern:     "a$",
-		expected:    []string{"a"},
-	},
+	args        []string
+	input       string
+	expected    string
 }
 
-var testPatternWithQuantifierExtraction = []struct {
-	description string
-	pattern     string
-	expected    []Pattern
-}{
-	{
-		description: "VComplex capture group",
-		pattern:     "((c.t|d.g) and (f..h|b..d)), \\2 with \\3, \\1",
-		expected:    []Pattern{{pattern: "((c.t|d.g) and (f..h|b..d))", sign: Alternation}, {pattern: ", "}, {pattern: "\\2"}, {pattern: " with "}, {pattern: "\\3"}, {pattern: ", "}, {pattern: "\\1"}},
-	},
-	{
-		description: "VComplex capture group",
-		pattern:     "(([abc]+)-([def]+)) is \\1, not ([^xyz]+), \\2, or \\3",
-		expected:    []Pattern{{pattern: "(([abc]+)-([def]+))"}, {pattern: " is "}, {pattern: "\\1"}, {pattern: ", not "}, {pattern: "([^xyz]+)"}, {pattern: ", "}, {pattern: "\\2"}, {pattern: ", or "}, {pattern: "\\3"}},
-	},
-	{
-		description: "VComplex capture group",
-		pattern:     "('(cat) and \\2') is the same as \\1",
-		expected:    []Pattern{{pattern: "('(cat) and \\2')"}, {pattern: " is the same as "}, {pattern: "\\1"}},
-	},
+var testOutputPrefix = []outputTest{
 	{
-		description: "Complex capture group",
-		pattern:     "(\\w+ ca+t)",
-		expected:    []Pattern{{pattern: "(\\w+ ca+t)"}},
+		description: "line number",
+		args:        []string{"-n", "\\d+"},
+		input:       "foo\nbar 12\nbaz 3\n",
+		expected:    "2:bar 12\n3:baz 3\n",
 	},
 	{
-		description: "Complex capture group",
-		pattern:     "(\\w+ \\d+) is doing \\1 times",
-		expected:    []Pattern{{pattern: "(\\w+ \\d+)"}, {pattern: " is doing "}, {pattern: "\\1"}, {pattern: " times"}},
+		description: "byte offset of the line",
+		args:        []string{"-b", "\\d+"},
+		input:       "foo\nbar 12\nbaz 3\n",
+		expected:    "4:bar 12\n11:baz 3\n",
 	},
 	{
-		description: "Complex capture group",
-		pattern:     "([abcd]+) is \\1, not [^xyz]+",
-		expected:    []Pattern{{pattern: "([abcd]+)"}, {pattern: " is "}, {pattern: "\\1"}, {pattern: ", not "}, {pattern: "[^xyz]", sign: Plus}},
+		description: "byte offset of the matches",
+		args:        []string{"-ob", "\\d+"},
+		input:       "foo\nbar 12 34\n",
+		expected:    "8:12\n11:34\n",
 	},
 	{
-		description: "Complex capture group",
-		pattern:     "(\\w\\w\\w\\w \\d\\d\\d) is doing \\1 times",
-		expected:    []Pattern{{pattern: "(\\w\\w\\w\\w \\d\\d\\d)"}, {pattern: " is doing "}, {pattern: "\\1"}, {pattern: " times"}},
-	},
-	{
-		description: "Negative character group",
-		pattern:     "^[^xyz]",
-		expected:    []Pattern{{pattern: "[^xyz]"}},
-	},
-	{
-		description: "single backreference",
-		pattern:     "(\\w+) and \\1",
-		expected:    []Pattern{{pattern: "(\\w+)"}, {pattern: " and "}, {pattern: "\\1"}},
-	},
-	{
-		description: "single backreference",
-		pattern:     "(cat) and \\1",
-		expected:    []Pattern{{pattern: "(cat)"}, {pattern: " and "}, {pattern: "\\1"}},
-	},
-	{
-		description: "one alternation",
-		pattern:     "a (cat|dog)",
-		expected:    []Pattern{{pattern: "a "}, {pattern: "(cat|dog)", sign: Alternation}},
-	},
-	{
-		description: "one alternation",
-		pattern:     "(a|c)",
-		expected:    []Pattern{{pattern: "(a|c)", sign: Alternation}},
-	},
-	{
-		description: "one quantifier",
-		pattern:     "a+bc",
-		expected:    []Pattern{{pattern: "a", sign: Plus}, {pattern: "bc"}},
-	},
-	{
-		description: "one quantifier",
-		pattern:     "ab+cd",
-		expected:    []Pattern{{pattern: "a"}, {pattern: "b", sign: Plus}, {pattern: "cd"}},
-	},
-	{
-		description: "one quantifier",
-		pattern:     "ab?cd",
-		expected:    []Pattern{{pattern: "a"}, {pattern: "b", sign: Optional}, {pattern: "cd"}},
+		description: "column of the first match",
+		args:        []string{"-nH", "--column", "\\d+"},
+		input:       "foo\nbar 12 34\n",
+		expected:    "file:2:5:bar 12 34\n",
 	},
 }
 
-var testGrep = []struct {
-	description string
-	pattern     string
-	line        string
-	expected    bool
-}{
-	{
-		description: "complex nested backreference",
-		line:        "cat and fish, cat with fish, cat and fish",
-		pattern:     "((c.t|d.g) and (f..h|b..d)), \\2 with \\3, \\1",
-		expected:    true,
-	},
-	{
-		description: "complex nested backreference",
-		line:        "abc-def is abc-def, not xyz, abc, or def",
-		pattern:     "(([abc]+)-([def]+)) is \\1, not ([^xyz]+), \\2, or \\3",
-		expected:    false,
-	},
-	{
-		description: "complex nested backreference",
-		line:        "abc-def is abc-def, not efg, abc, or def",
-		pattern:     "(([abc]+)-([def]+)) is \\1, not ([^xyz]+), \\2, or \\3",
-		expected:    true,
-	},
-	{
-		description: "nested backreference",
-		line:        "grep 101 is doing grep 101 times, and again grep 101 times",
-		pattern:     "((\\w\\w\\w\\w) (\\d\\d\\d)) is doing \\2 \\3 times, and again \\1 times",
-		expected:    true,
-	},
+var testContext = []outputTest{
 	{
-		description: "nested backreference",
-		line:        "'cat and cat' is the same as 'cat and cat'",
-		pattern:     "('(cat) and \\2') is the same as \\1",
-		expected:    true,
+		description: "after context",
+		args:        []string{"-n", "-A", "1", "x"},
+		input:       "x\na\nb\nx\nc\n",
+		expected:    "1:x\n2-a\n--\n4:x\n5-c\n",
 	},
 	{
-		description: "complex single backreference",
-		line:        "bugs here and bugs there",
-		pattern:     "(b..s|c..e) here and \\1 there",
-		expected:    true,
+		description: "before context",
+		args:        []string{"-B1", "x"},
+		input:       "a\nb\nx\nc\nd\nx\n",
+		expected:    "b\nx\n--\nd\nx\n",
 	},
 	{
-		description: "complex single backreference",
-		line:        "this starts and ends with this",
-		pattern:     "^(\\w+) starts and ends with \\1$",
-		expected:    true,
+		description: "overlapping contexts are merged",
+		args:        []string{"-C", "1", "x"},
+		input:       "a\nx\nb\nx\nc\nd\n",
+		expected:    "a\nx\nb\nx\nc\n",
 	},
 	{
-		description: "single backreference",
-		line:        "abcd is abcd, not efg",
-		pattern:     "([abcd]+) is \\1, not [^xyz]+",
-		expected:    true,
+		description: "adjacent contexts are not separated",
+		args:        []string{"-C1", "x"},
+		input:       "x\na\nb\nx\n",
+		expected:    "x\na\nb\nx\n",
 	},
 	{
-		description: "single backreference",
-		line:        "grep 101 is doing grep 101 times",
-		pattern:     "(\\w\\w\\w\\w \\d\\d\\d) is doing \\1 times",
-		expected:    true,
+		description: "custom group separator",
+		args:        []string{"--group-separator=##", "-A1", "x"},
+		input:       "x\na\nb\nx\n",
+		expected:    "x\na\n##\nx\n",
 	},
 	{
-		description: "single backreference",
-		pattern:     "(cat) and \\1",
-		line:        "cat and cat",
-		expected:    true,
-	},
-	{
-		description: "single backreference",
-		pattern:     "(cat) and \\1",
-		line:        "cat and dog",
-		expected:    false,
-	},
-	{
-		description: "alternation",
-		pattern:     "a (cat|dog)",
-		line:        "a dog",
-		expected:    true,
-	},
-	{
-		description: "alternation",
-		pattern:     "a (cat|dog)",
-		line:        "a cat",
-		expected:    true,
-	},
-	{
-		description: "alternation",
-		pattern:     "a (cat|dog)",
-		line:        "a rat",
-		expected:    false,
-	},
-	{
-		description: "wildcard character",
-		pattern:     "c.t",
-		line:        "car",
-		expected:    false,
+		description: "no group separator",
+		args:        []string{"--no-group-separator", "--after-context", "1", "x"},
+		input:       "x\na\nb\nx\n",
+		expected:    "x\na\nx\n",
 	},
+}
+
+var testColor = []outputTest{
 	{
-		description: "wildcard character",
-		pattern:     "c.t",
-		line:        "cut",
-		expected:    true,
+		description: "colored matches",
+		args:        []string{"--color=always", "-n", "\\d+"},
+		input:       "a 1 b 22\n",
+		expected:    "\033[32m\033[K1\033[m\033[K\033[36m\033[K:\033[m\033[Ka \033[01;31m\033[K1\033[m\033[K b \033[01;31m\033[K22\033[m\033[K\n",
 	},
+}
+
+var testWholeMatch = []outputTest{
 	{
-		description: "optional character",
-		pattern:     "ca?t",
-		line:        "act",
-		expected:    true,
+		description: "whole words",
+		args:        []string{"-w", "cat"},
+		input:       "cats\nthe cat\nbobcat\ncat_\n",
+		expected:    "the cat\n",
 	},
 	{
-		description: "match one or more characters",
-		pattern:     "ca+t",
-		line:        "caaats",
-		expected:    true,
+		description: "whole words retry a later match",
+		args:        []string{"-ow", "c\\w\\w"},
+		input:       "bobcat cat\n",
+		expected:    "cat\n",
 	},
 	{
-		description: "single character match",
-		pattern:     "d",
-		line:        "dog",
-		expected:    true,
+		description: "whole line",
+		args:        []string{"-x", "\\d+"},
+		input:       "123\n123a\na123\n",
+		expected:    "123\n",
 	},
 	{
-		description: "Combining character classes",
-		pattern:     "\\d+ apple",
-		line:        "sally has 33 apples",
-		expected:    true,
+		description: "whole line tries the next alternative",
+		args:        []string{"-x", "(a|ab)"},
+		input:       "ab\na\nabc\n",
+		expected:    "ab\na\n",
 	},
+}
+
+var testMaxCount = []outputTest{
 	{
-		description: "Combining character classes",
-		pattern:     "\\d apple",
-		line:        "sally has 3 apples",
-		expected:    true,
+		description: "max count",
+		args:        []string{"-m", "2", "x"},
+		input:       "x\nx\nx\n",
+		expected:    "x\nx\n",
 	},
 	{
-		description: "Combining character classes",
-		pattern:     "\\d \\w\\w\\ws",
-		line:        "sally has 3 dogs",
-		expected:    true,
+		description: "max count with after context",
+		args:        []string{"-m1", "-A1", "x"},
+		input:       "x\na\nx\n",
+		expected:    "x\na\n",
 	},
+}
+
+var testReplace = []outputTest{
 	{
-		description: "Combining character classes",
-		pattern:     "\\d \\w\\w\\ws",
-		line:        "sally has 1 dog",
-		expected:    false,
+		description: "replaced matches",
+		args:        []string{"--replace", "<$1>", "(\\d+)"},
+		input:       "a 1 b 22\nc\n",
+		expected:    "a <1> b <22>\n",
 	},
 	{
-		description: "Negative character group",
-		pattern:     "^[^xyz]",
-		line:        "apple",
-		expected:    true,
+		description: "replaced matches only",
+		args:        []string{"-o", "--replace=\\2-\\1", "(\\w)(\\d)"},
+		input:       "a1 b2\n",
+		expected:    "1-a\n2-b\n",
 	},
 	{
-		description: "Negative character group",
-		pattern:     "^[^xyz]",
-		line:        "xpple",
-		expected:    false,
+		description: "colored replacements",
+		args:        []string{"--color=always", "--replace=#", "\\d+"},
+		input:       "a1b\n",
+		expected:    "a\033[01;31m\033[K#\033[m\033[Kb\n",
 	},
 	{
-		description: "Negative character group",
-		pattern:     "[^xyz]",
-		line:        "xxe",
-		expected:    true,
+		description: "split fields",
+		args:        []string{"-n", "--split", ", ?"},
+		input:       "a, b,c\nd\n",
+		expected:    "1:a\n1:b\n1:c\n",
 	},
+}
+
+var testNullData = []outputTest{
 	{
-		description: "Negative character group",
-		pattern:     "[^xyz]",
-		line:        "apple",
-		expected:    true,
+		description: "null data",
+		args:        []string{"--null-data", "-n", "^b.c$"},
+		input:       "a\x00b\nc\x00bxc",
+		expected:    "2:b\nc\x003:bxc\x00",
 	},
 	{
-		description: "Negative character group",
-		pattern:     "[^anb]",
-		line:        "banana",
-		expected:    false,
+		description: "null after the file name",
+		args:        []string{"-HZ", "x"},
+		input:       "x\n",
+		expected:    "file\x00x\n",
 	},
+}
+
+var testPerMatchFormats = []outputTest{
 	{
-		description: "Positive Character Group",
-		pattern:     "[abcd]",
-		line:        "a",
-		expected:    true,
+		description: "vimgrep repeats the line for each match",
+		args:        []string{"--vimgrep", "-C1", "o"},
+		input:       "a\nfoo\nb\n",
+		expected:    "file:2:2:foo\nfile:2:3:foo\n",
 	},
 	{
-		description: "start of string anchor",
-		pattern:     "^log",
-		line:        "log",
-		expected:    true,
+		description: "vimgrep columns in characters",
+		args:        []string{"--vimgrep", "--column-unit=char", "d"},
+		input:       "\u00e9t\u00e9 d\n",
+		expected:    "file:1:5:\u00e9t\u00e9 d\n",
 	},
 	{
-		description: "start of string anchor",
-		pattern:     "^log",
-		line:        "slog",
-		expected:    false,
+		description: "vimgrep columns in bytes",
+		args:        []string{"--vimgrep", "d"},
+		input:       "\u00e9t\u00e9 d\n",
+		expected:    "file:1:7:\u00e9t\u00e9 d\n",
 	},
 	{
-		description: "end of string anchor",
-		pattern:     "log$",
-		line:        "log",
-		expected:    true,
+		description: "vimgrep only matching",
+		args:        []string{"--vimgrep", "-o", "--column-unit=char", "o+"},
+		input:       "\u00e9 foo\n",
+		expected:    "file:1:4:oo\n",
 	},
 	{
-		description: "end of string anchor",
-		pattern:     "log$",
-		line:        "logs",
-		expected:    false,
+		description: "emacs compilation format",
+		args:        []string{"--format=emacs", "b"},
+		input:       "a\nab\n",
+		expected:    "file:2:2: ab\n",
 	},
 	{
-		description: "match quantifiers",
-		pattern:     "\\d+c",
-		line:        "33c",
-		expected:    true,
+		description: "column in characters",
+		args:        []string{"--column", "--column-unit=char", "d"},
+		input:       "\u00e9 d\n",
+		expected:    "3:\u00e9 d\n",
 	},
 }
 
-func getPatternStringFromPatterns(patterns []Pattern) []string {
-	out := make([]string, len(patterns))
-	for x, p := range patterns {
-		out[x] = p.pattern
+// the prefixes of the lines: -n, -b and --column
+func TestOutputPrefix(t *testing.T) {
+	runOutputTests(t, testOutputPrefix)
+}
+
+// -A, -B and -C, and the separators between the groups of lines
+func TestContext(t *testing.T) {
+	runOutputTests(t, testContext)
+}
+
+// --color
+func TestColor(t *testing.T) {
+	runOutputTests(t, testColor)
+}
+
+// -w and -x
+func TestWholeMatch(t *testing.T) {
+	runOutputTests(t, testWholeMatch)
+}
+
+// -m
+func TestMaxCount(t *testing.T) {
+	runOutputTests(t, testMaxCount)
+}
+
+// --replace and --split
+func TestReplace(t *testing.T) {
+	runOutputTests(t, testReplace)
+}
+
+// --null-data and -Z
+func TestNullData(t *testing.T) {
+	runOutputTests(t, testNullData)
+}
+
+// --vimgrep, --format=emacs and --column-unit
+func TestPerMatchFormats(t *testing.T) {
+	runOutputTests(t, testPerMatchFormats)
+}
+
+// search the input of each test with its arguments and compare the output
+func runOutputTests(t *testing.T, tests []outputTest) {
+	for _, tp := range tests {
+		t.Run(tp.description, func(t *testing.T) {
+			opts, err := parseArgs(tp.args)
+			if err != nil {
+				t.Fatalf("error returned: %s", err)
+			}
+			re, err := compilePattern(opts)
+			if err != nil {
+				t.Fatalf("error returned: %s", err)
+			}
+			var buf bytes.Buffer
+			out := bufio.NewWriter(&buf)
+			p := newPrinter(out, opts)
+			if opts.color == "always" {
+				p.colors = &defaultColors
+			}
+			grepLines(context.Background(), re, "file", newLineScanner(strings.NewReader(tp.input), opts), p)
+			out.Flush()
+			if buf.String() != tp.expected {
+				t.Fatalf("wrong output: got %q expected: %q", buf.String(), tp.expected)
+			}
+		})
+	}
+}
+
+// search the files of the arguments the way main does, returns the output, whether a line was selected
+// and the number of errors reported
+func runSearch(t *testing.T, args []string) (string, bool, int) {
+	t.Helper()
+	opts, err := parseArgs(args)
+	if err != nil {
+		t.Fatalf("error returned: %s", err)
+	}
+	re, err := compilePattern(opts)
+	if err != nil {
+		t.Fatalf("error returned: %s", err)
+	}
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+	errors := 0
+	selected := searchFiles(opts, re, nil, out, func(err error) { errors++ })
+	out.Flush()
+	return buf.String(), selected, errors
+}
+
+// the output of a search of the files of the arguments that reports no error
+func searchOutput(t *testing.T, args []string) string {
+	t.Helper()
+	output, _, errors := runSearch(t, args)
+	if errors > 0 {
+		t.Fatalf("%d errors reported", errors)
 	}
-	return out
+	return output
 }
 
-func TestSimplePatternExtraction(t *testing.T) {
-	for _, tp := range testSimplePatternExtraction {
+func TestContextBuffer(t *testing.T) {
+	cb := newContextBuffer(2)
+	for x := 1; x <= 5; x++ {
+		cb.push(contextLine{line_number: x})
+	}
+	lines := cb.drain()
+	if len(lines) != 2 || lines[0].line_number != 4 || lines[1].line_number != 5 {
+		t.Fatalf("expected the last 2 lines, got %v", lines)
+	}
+	if lines = cb.drain(); len(lines) != 0 {
+		t.Fatalf("expected an empty buffer, got %v", lines)
+	}
+	//the text is kept even when the buffer it comes from is reused
+	text := []byte("before")
+	cb.push(contextLine{line_number: 6, text: text})
+	copy(text, "reused")
+	if lines = cb.drain(); string(lines[0].text) != "before" {
+		t.Fatalf("expected a copy of the text, got %q", lines[0].text)
+	}
+}
+
+func TestParseGrepColors(t *testing.T) {
+	c := parseGrepColors("mt=01;32:sl=1:fn=:ne:xx=5")
+	expected := defaultColors
+	expected.selected_match, expected.context_match = "01;32", "01;32"
+	expected.selected_line = "1"
+	expected.filename = ""
+	expected.no_erase = true
+	if c != expected {
+		t.Fatalf("wrong colors: got %+v expected: %+v", c, expected)
+	}
+}
+
+func TestSearchFiles(t *testing.T) {
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "first"), filepath.Join(dir, "second")
+	os.WriteFile(first, []byte("a\nx 1\n"), 0o644)
+	os.WriteFile(second, []byte("x 2\nb\n"), 0o644)
+	missing := filepath.Join(dir, "missing")
+	binary := filepath.Join(dir, "binary")
+	os.WriteFile(binary, []byte("x 3\x00\n"), 0o644)
+	gzipped, zlibbed, bzipped := filepath.Join(dir, "log.gz"), filepath.Join(dir, "zlib"), filepath.Join(dir, "log.bz2")
+	writeCompressed(t, gzipped, "a\nx 4\n", func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) })
+	writeCompressed(t, zlibbed, "x 6\n", func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) })
+	os.WriteFile(bzipped, []byte("\x42\x5a\x68\x39\x31\x41\x59\x26\x53\x59\x33\xcd\x74\xf9\x00\x00\x02\x59\x80\x00\x10\x40\x00\x02\x00\x20\x00\x00\x40\x20\x00\x21\x83\x41\x9a\x0b\x80\x5c\x5d\xc9\x14\xe1\x42\x40\xcf\x35\xd3\xe4"), 0o644)
+	//text starting like a compressed stream
+	bzh, gzh := filepath.Join(dir, "bzh"), filepath.Join(dir, "gzh")
+	os.WriteFile(bzh, []byte("BZhello x 7\n"), 0o644)
+	os.WriteFile(gzh, []byte("\x1f\x8b x 8\n"), 0o644)
+
+	var tests = []struct {
+		description string
+		args        []string
+		expected    string
+		selected    bool
+		errors      int
+	}{
+		{description: "files in order", args: []string{"x", first, second}, expected: first + ":x 1\n" + second + ":x 2\n", selected: true},
+		{description: "separator between files", args: []string{"-hB1", "x", first, second}, expected: "a\nx 1\n--\nx 2\n", selected: true},
+		{description: "errors are reported", args: []string{"x", missing, second}, expected: second + ":x 2\n", selected: true, errors: 1},
+		{description: "quiet", args: []string{"-q", "x", first, second}, expected: "", selected: true},
+		{description: "no selected line", args: []string{"-q", "y", first, second}, expected: "", selected: false},
+		{description: "binary file", args: []string{"x", binary, second}, expected: "Binary file " + binary + " matches\n" + second + ":x 2\n", selected: true},
+		{description: "binary file as text", args: []string{"-a", "-h", "x", binary}, expected: "x 3\x00\n", selected: true},
+		{description: "binary file without match", args: []string{"-I", "x", binary, second}, expected: second + ":x 2\n", selected: true},
+		{description: "compressed files", args: []string{"-znb", "x", gzipped, zlibbed, bzipped}, expected: gzipped + ":2:2:x 4\n" + zlibbed + ":1:0:x 6\n" + bzipped + ":2:2:x 5\n", selected: true},
+		{description: "text looking compressed", args: []string{"-za", "x", bzh, gzh}, expected: bzh + ":BZhello x 7\n" + gzh + ":\x1f\x8b x 8\n", selected: true},
+		{description: "binary file without selected line", args: []string{"--binary-files=binary", "y", binary}, expected: "", selected: false},
+	}
+	for _, tp := range tests {
 		t.Run(tp.description, func(t *testing.T) {
-			gh := newGrepHandler([]byte("balls"), tp.pattern)
-			ok := gh.ExtractPatterns()
-			if !ok {
-				t.Fatal("no pattern found")
+			output, selected, errors := runSearch(t, tp.args)
+			if selected != tp.selected || errors != tp.errors {
+				t.Fatalf("got selected %v with %d errors, expected: %v with %d errors", selected, errors, tp.selected, tp.errors)
+			} else if output != tp.expected {
+				t.Fatalf("wrong output: got %q expected: %q", output, tp.expected)
+			}
+		})
+	}
+}
+
+func TestFileOutput(t *testing.T) {
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+	fo := &fileOutput{}
+	fo.Write([]byte("early\n"))
+	if out.Buffered() != 0 {
+		t.Fatalf("the output of a file ahead of its turn was written")
+	}
+	separators := 0
+	fo.direct(out, func() {
+		separators++
+		out.WriteString("--\n")
+	})
+	fo.Write([]byte("late\n"))
+	//the file is still searched, what it printed so far is flushed as soon as it is at the head of the queue
+	if buf.String() != "--\nearly\n" || separators != 1 {
+		t.Fatalf("got %q with %d separators", buf.String(), separators)
+	}
+	fo.Flush()
+	if buf.String() != "--\nearly\nlate\n" {
+		t.Fatalf("got %q", buf.String())
+	}
+}
+
+func TestJSON(t *testing.T) {
+	dir := t.TempDir()
+	text, latin1, binary, other := filepath.Join(dir, "text"), filepath.Join(dir, "latin1"), filepath.Join(dir, "binary"), filepath.Join(dir, "other")
+	os.WriteFile(text, []byte("one\ntwo\nthree\n"), 0o644)
+	os.WriteFile(latin1, []byte("caf\xe9 to\n"), 0o644)
+	os.WriteFile(binary, []byte("to\x00\n"), 0o644)
+	os.WriteFile(other, []byte("nothing\n"), 0o644)
+
+	output := searchOutput(t, []string{"--json", "-A1", "t[wo]", text, latin1, binary, other})
+
+	//the elapsed times change from one run to the next, the other fields are checked
+	var actual []string
+	for _, line := range strings.Split(strings.TrimSuffix(output, "\n"), "\n") {
+		var msg struct {
+			Type string
+			Data struct {
+				Path         jsonData
+				Lines        jsonData
+				LineNumber   int `json:"line_number"`
+				Submatches   []jsonSubmatch
+				BinaryOffset *int `json:"binary_offset"`
+				Stats        searchStats
+			}
+		}
+		if err := json.Unmarshal([]byte(line), &msg); err != nil {
+			t.Fatalf("invalid message %q: %s", line, err)
+		}
+		d := msg.Data
+		switch msg.Type {
+		case "begin":
+			actual = append(actual, fmt.Sprintf("begin %s", filepath.Base(d.Path["text"])))
+		case "match", "context":
+			actual = append(actual, fmt.Sprintf("%s %d %v %v", msg.Type, d.LineNumber, d.Lines, d.Submatches))
+		case "end":
+			actual = append(actual, fmt.Sprintf("end %v %d/%d/%d", d.BinaryOffset != nil, d.Stats.BytesSearched, d.Stats.MatchedLines, d.Stats.Matches))
+		default:
+			actual = append(actual, fmt.Sprintf("%s %d/%d/%d", msg.Type, d.Stats.Searches, d.Stats.SearchesWithMatch, d.Stats.MatchedLines))
+		}
+	}
+	expected := []string{
+		"begin text",
+		"match 2 map[text:two\n] [{map[text:tw] 0 2}]",
+		"context 3 map[text:three\n] []",
+		"end false 14/1/1",
+		"begin latin1",
+		"match 1 map[bytes:Y2Fm6SB0bwo=] [{map[text:to] 5 7}]",
+		"end false 8/1/1",
+		"begin binary",
+		"end true 4/0/0",
+		"summary 4/3/2",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("got %q expected: %q", actual, expected)
+	}
+}
+
+func TestPatternSet(t *testing.T) {
+	dir := t.TempDir()
+	pattern_file := filepath.Join(dir, "patterns")
+	os.WriteFile(pattern_file, []byte("d\\w+\ncat\n"), 0o644)
+	tests := []struct {
+		args     []string
+		line     string
+		expected [][]int
+	}{
+		{[]string{"-e", "cat", "-e", "dog"}, "cat sat dogs", [][]int{{0, 3}, {8, 11}}},
+		{[]string{"-e", "ca", "-e", "cat s"}, "cat sat dogs", [][]int{{0, 5}}},
+		{[]string{"-f", pattern_file}, "cat sat dogs", [][]int{{0, 3}, {8, 12}}},
+		{[]string{"-f", pattern_file, "-e", "s\\w+"}, "cat sat dogs", [][]int{{0, 3}, {4, 7}, {8, 12}}},
+		{[]string{"-f", os.DevNull}, "cat sat dogs", nil},
+		//aa is searched again after ba, from the middle of its first match
+		{[]string{"-e", "aa", "-e", "ba"}, "baaa", [][]int{{0, 2}, {2, 4}}},
+	}
+	for _, test := range tests {
+		t.Run(strings.Join(test.args, " "), func(t *testing.T) {
+			opts, err := parseArgs(test.args)
+			if err != nil {
+				t.Fatalf("error returned: %s", err)
+			}
+			re, err := compilePattern(opts)
+			if err != nil {
+				t.Fatalf("error returned: %s", err)
+			}
+			var actual [][]int
+			for _, match := range re.FindAllSubmatchIndex([]byte(test.line), -1) {
+				actual = append(actual, match[:2])
+			}
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Fatalf("got %v expected: %v", actual, test.expected)
+			}
+		})
+	}
+
+	//each match ends with the index of the pattern that found it
+	set, _ := compilePattern(&options{patterns: []string{"aa", "b(a)"}})
+	if actual := set.FindAllSubmatchIndex([]byte("baaa"), -1); !reflect.DeepEqual(actual, [][]int{{0, 2, 1, 2, 1}, {2, 4, 0}}) {
+		t.Fatalf("got %v", actual)
+	}
+	if actual := set.Split("xbaaay", -1); !reflect.DeepEqual(actual, []string{"x", "", "y"}) {
+		t.Fatalf("split: got %q", actual)
+	}
+}
+
+func TestSARIF(t *testing.T) {
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "first"), filepath.Join(dir, "second")
+	os.WriteFile(first, []byte("a cat\ncaf\u00e9 dog\n"), 0o644)
+	os.WriteFile(second, []byte("dog\n"), 0o644)
+	output := searchOutput(t, []string{"--format=sarif", "-e", "dog", "-e", "\u00e9 d", first, second})
+
+	var log sarifLog
+	if err := json.Unmarshal([]byte(output), &log); err != nil {
+		t.Fatalf("invalid log: %s", err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 || len(log.Runs[0].Tool.Driver.Rules) != 2 {
+		t.Fatalf("wrong log: %s", output)
+	}
+	var actual []string
+	for _, result := range log.Runs[0].Results {
+		location := result.Locations[0].PhysicalLocation
+		r := location.Region
+		actual = append(actual, fmt.Sprintf("%s %s %d:%d-%d:%d", result.RuleID, filepath.Base(location.ArtifactLocation.URI), r.StartLine, r.StartColumn, r.EndLine, r.EndColumn))
+	}
+	//the columns count the characters, é is a single one
+	expected := []string{
+		"pattern-2 first 2:4-2:7",
+		"pattern-1 first 2:6-2:9",
+		"pattern-1 second 1:1-1:4",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("got %q expected: %q", actual, expected)
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "file")
+	os.WriteFile(filename, []byte("alice@example bob@test\nnone\ncaf\u00e9 x\n"), 0o644)
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"--format={line}:{column}:{offset}:{match}", "\\w+@"}, "1:1:0:alice@\n1:15:14:bob@\n"},
+		{[]string{"--format={user} at {2}", "(?<user>\\w+)@(\\w+)"}, "alice at example\nbob at test\n"},
+		{[]string{"--format={pattern}:{match}", "-e", "bob", "-e", "x\\w+"}, "2:xample\n1:bob\n"},
+		{[]string{"--format={{{text}}}", "none"}, "{none}\n"},
+		{[]string{"--format={column}", "--column-unit=char", "x"}, "8\n6\n"},
+	}
+	for _, test := range tests {
+		t.Run(strings.Join(test.args, " "), func(t *testing.T) {
+			if output := searchOutput(t, append(test.args, filename)); output != test.expected {
+				t.Fatalf("got %q expected: %q", output, test.expected)
+			}
+		})
+	}
+
+	for _, format := range []string{"--format={path", "--format={}"} {
+		if _, err := parseArgs([]string{format, "a"}); err == nil {
+			t.Errorf("%s: no error returned", format)
+		}
+	}
+	opts, _ := parseArgs([]string{"--format={user}", "(\\w+)@"})
+	if _, err := compilePattern(opts); err == nil {
+		t.Errorf("no error returned for an unknown field")
+	}
+}
+
+func TestExplain(t *testing.T) {
+	opts, err := parseArgs([]string{"--explain", "-e", "(?<n>\\d+)x?", "-e", "a|b"})
+	if err != nil {
+		t.Fatalf("error returned: %s", err)
+	}
+	var buf bytes.Buffer
+	if err := explainPatterns(opts, &buf); err != nil {
+		t.Fatalf("error returned: %s", err)
+	}
+	expected := "pattern `(?<n>\\d+)x?` 0-11\n" +
+		"  group `(?<n>\\d+)` 0-9 group=1 name=n\n" +
+		"    digit `\\d+` 5-8 quantifier=+\n" +
+		"  literal `x?` 9-11 quantifier=?\n" +
+		"pattern `a|b` 0-3\n" +
+		"  literal `a|b` 0-3\n"
+	if buf.String() != expected {
+		t.Fatalf("got %q expected: %q", buf.String(), expected)
+	}
+
+	opts, _ = parseArgs([]string{"--explain=json", "a+"})
+	buf.Reset()
+	explainPatterns(opts, &buf)
+	var root regex.Node
+	if err := json.Unmarshal(buf.Bytes(), &root); err != nil || len(root.Children) != 1 || root.Children[0].Quantifier != "+" {
+		t.Fatalf("wrong tree: %s", buf.String())
+	}
+	if _, err := parseArgs([]string{"--explain=xml", "a"}); err == nil {
+		t.Fatalf("no error returned for an invalid format")
+	}
+}
+
+func TestEditFile(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "file")
+	os.WriteFile(filename, []byte("cat 1\ndog\ncat 22\n"), 0o600)
+	re, err := compilePattern(&options{patterns: []string{"(\\w+) (\\d+)"}})
+	if err != nil {
+		t.Fatalf("error returned: %s", err)
+	}
+
+	selected, err := editFile(re, filename, &options{replace: []byte("$2 ${1}s"), backup_suffix: ".bak"})
+	if err != nil || !selected {
+		t.Fatalf("got %v %v expected: true <nil>", selected, err)
+	}
+	if content, _ := os.ReadFile(filename); string(content) != "1 cats\ndog\n22 cats\n" {
+		t.Fatalf("wrong content: %q", content)
+	}
+	if backup, _ := os.ReadFile(filename + ".bak"); string(backup) != "cat 1\ndog\ncat 22\n" {
+		t.Fatalf("wrong backup: %q", backup)
+	}
+	if info, _ := os.Stat(filename); info.Mode().Perm() != 0o600 {
+		t.Fatalf("wrong mode: %v", info.Mode())
+	}
+
+	//a file without a match is left untouched
+	if selected, err := editFile(re, filename, &options{replace: []byte("x"), backup_suffix: ".orig"}); err != nil || selected {
+		t.Fatalf("got %v %v expected: false <nil>", selected, err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Fatalf("expected only the file and its backup, got %v", entries)
+	}
+}
+
+func TestBufferScanner(t *testing.T) {
+	input := "a1\nb\nc\nd2\ne3\nf\ng\nh\ni\nj4\nk\n^l\nm"
+	tests := [][]string{
+		{"-n", "\\d"},
+		{"-nb", "-C1", "\\d"},
+		{"-A2", "\\d"},
+		{"-B3", "-m2", "\\d"},
+		{"-n", "^[^\\d]$"},
+		{"-o", "-n", "c|m"},
+		{"-n", "-A1", "m"},
+		{"zzz"},
+	}
+	for _, args := range tests {
+		t.Run(strings.Join(args, " "), func(t *testing.T) {
+			opts, err := parseArgs(args)
+			if err != nil {
+				t.Fatalf("error returned: %s", err)
+			}
+			re, err := compilePattern(opts)
+			if err != nil {
+				t.Fatalf("error returned: %s", err)
+			}
+			output := func(lines lineSource) string {
+				var buf bytes.Buffer
+				out := bufio.NewWriter(&buf)
+				grepLines(context.Background(), re, "file", lines, newPrinter(out, opts))
+				out.Flush()
+				return buf.String()
+			}
+			expected := output(regex.NewLineScanner(strings.NewReader(input)))
+			if actual := output(newBufferScanner(re, []byte(input), opts)); actual != expected {
+				t.Fatalf("got %q expected: %q", actual, expected)
+			}
+			if actual := output(newBufferScanner(re, []byte(input+"\n"), opts)); actual != expected {
+				t.Fatalf("with a final newline: got %q expected: %q", actual, expected)
+			}
+		})
+	}
+}
+
+func TestMultiline(t *testing.T) {
+	input := "one\ntwo\nthree\nfour\nfive\nsix\n"
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"-U", "-n", "o\nt"}, "2:two\nthree\n"},
+		{[]string{"-U", "-n", "o.t"}, ""},
+		{[]string{"-U", "-n", "(?s)o.t"}, "2:two\nthree\n"},
+		{[]string{"-U", "-n", "(?m)^f\\w+$"}, "4:four\n5:five\n"},
+		{[]string{"-U", "-n", "^t\\w+"}, ""},
+		{[]string{"-U", "-n", "-C1", "e\nf"}, "2-two\n3:three\nfour\n5-five\n"},
+		{[]string{"-U", "-b", "r\nf"}, "14:four\nfive\n"},
+		{[]string{"-U", "-o", "e\nf"}, "e\nf\n"},
+		{[]string{"-U", "--replace=-", "o\nt"}, "tw-hree\n"},
+		{[]string{"-n", "o\nt"}, ""},
+		{[]string{"-U", "--vimgrep", "e\nf"}, "file:3:5:three\n"},
+	}
+	for _, tp := range tests {
+		t.Run(strings.Join(tp.args, " "), func(t *testing.T) {
+			opts, err := parseArgs(tp.args)
+			if err != nil {
+				t.Fatalf("error returned: %s", err)
+			}
+			re, err := compilePattern(opts)
+			if err != nil {
+				t.Fatalf("error returned: %s", err)
+			}
+			var lines lineSource = newMultilineScanner(re, []byte(input))
+			if !opts.multiline {
+				lines = newLineScanner(strings.NewReader(input), opts)
 			}
-			patterns := getPatternStringFromPatterns(gh.patterns)
-			if !reflect.DeepEqual(patterns, tp.expected) {
-				t.Fatalf("no matching pattern: got %v expected: %v", gh.patterns, tp.expected)
+			var buf bytes.Buffer
+			out := bufio.NewWriter(&buf)
+			grepLines(context.Background(), re, "file", lines, newPrinter(out, opts))
+			out.Flush()
+			if buf.String() != tp.expected {
+				t.Fatalf("got %q expected: %q", buf.String(), tp.expected)
 			}
 		})
 	}
 }
 
-func TestPatternWithQuantifierExtraction(t *testing.T) {
-	for _, tp := range testPatternWithQuantifierExtraction {
+func TestWalkFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		".gitignore":        "*.log\nbuild/\n",
+		".git/info/exclude": "secret.txt\n",
+		".hidden.txt":       "",
+		"a.txt":             "",
+		"debug.log":         "",
+		"secret.txt":        "",
+		"build/out.txt":     "",
+		"sub/.ignore":       "!keep.log\n",
+		"sub/keep.log":      "",
+		"sub/other.log":     "",
+		"sub/b.txt":         "",
+	}
+	for name, content := range files {
+		filename := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(filename), 0o755)
+		os.WriteFile(filename, []byte(content), 0o644)
+	}
+
+	var tests = []struct {
+		description string
+		args        []string
+		expected    []string
+	}{
+		{description: "ignore files and hidden files", args: []string{"-r", "x", dir}, expected: []string{"a.txt", "sub/b.txt", "sub/keep.log"}},
+		{description: "no ignore", args: []string{"-r", "--no-ignore", "x", dir}, expected: []string{"a.txt", "build/out.txt", "debug.log", "secret.txt", "sub/b.txt", "sub/keep.log", "sub/other.log"}},
+		{description: "hidden", args: []string{"-r", "--hidden", "x", dir}, expected: []string{".gitignore", ".hidden.txt", "a.txt", "sub/.ignore", "sub/b.txt", "sub/keep.log"}},
+		{description: "operands are always searched", args: []string{"-r", "x", filepath.Join(dir, "debug.log")}, expected: []string{"debug.log"}},
+		{description: "without -r", args: []string{"x", dir}, expected: []string{"."}},
+		{description: "include", args: []string{"-r", "--include=*.txt", "x", dir}, expected: []string{"a.txt", "sub/b.txt"}},
+		{description: "exclude", args: []string{"-r", "--exclude=sub/*", "x", dir}, expected: []string{"a.txt"}},
+	}
+	for _, tp := range tests {
 		t.Run(tp.description, func(t *testing.T) {
-			gh := newGrepHandler([]byte("balls"), tp.pattern)
-			ok := gh.ExtractPatterns()
-			if !ok {
-				t.Fatal("no pattern found")
-			} else if !reflect.DeepEqual(gh.patterns, tp.expected) {
-				t.Fatalf("no matching pattern: got %v expected: %v", gh.patterns, tp.expected)
+			opts, err := parseArgs(tp.args)
+			if err != nil {
+				t.Fatalf("error returned: %s", err)
+			}
+			var visited []string
+			walkFiles(opts, func(filename string, err error) bool {
+				rel, _ := filepath.Rel(dir, filename)
+				visited = append(visited, filepath.ToSlash(rel))
+				return true
+			})
+			if !reflect.DeepEqual(visited, tp.expected) {
+				t.Fatalf("got %q expected: %q", visited, tp.expected)
 			}
 		})
 	}
 }
 
-func TestGreps(t *testing.T) {
-	for _, tp := range testGrep {
+func writeCompressed(t *testing.T, filename, content string, compressor func(w io.Writer) io.WriteCloser) {
+	var buf bytes.Buffer
+	w := compressor(&buf)
+	w.Write([]byte(content))
+	w.Close()
+	if err := os.WriteFile(filename, buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("error returned: %s", err)
+	}
+}
+
+func TestSearchArchives(t *testing.T) {
+	dir := t.TempDir()
+	members := []struct{ name, content string }{
+		{"a.txt", "x 1\n"},
+		{"dir/b.log", "y\nx 2\n"},
+		{"dir/c.txt", "z\n"},
+	}
+	zipped := filepath.Join(dir, "archive.jar")
+	var zip_buf bytes.Buffer
+	zw := zip.NewWriter(&zip_buf)
+	zw.Create("dir/")
+	for _, member := range members {
+		w, _ := zw.Create(member.name)
+		w.Write([]byte(member.content))
+	}
+	zw.Close()
+	os.WriteFile(zipped, zip_buf.Bytes(), 0o644)
+	tarred := filepath.Join(dir, "archive.tar.gz")
+	writeCompressed(t, tarred, "", func(w io.Writer) io.WriteCloser {
+		gz := gzip.NewWriter(w)
+		tw := tar.NewWriter(gz)
+		for _, member := range members {
+			tw.WriteHeader(&tar.Header{Name: member.name, Mode: 0o644, Size: int64(len(member.content)), Typeflag: tar.TypeReg})
+			tw.Write([]byte(member.content))
+		}
+		tw.Close()
+		return gz
+	})
+
+	var tests = []struct {
+		description string
+		args        []string
+		expected    string
+	}{
+		{description: "zip", args: []string{"-zn", "x", zipped}, expected: zipped + "!a.txt:1:x 1\n" + zipped + "!dir/b.log:2:x 2\n"},
+		{description: "tar.gz", args: []string{"-zh", "x", tarred}, expected: "x 1\nx 2\n"},
+		{description: "include applies to members", args: []string{"-z", "--include=*.log", "x", zipped}, expected: zipped + "!dir/b.log:x 2\n"},
+		{description: "exclude applies to members", args: []string{"-z", "--exclude=dir", "--exclude=a.txt", "x", tarred}, expected: tarred + "!dir/b.log:x 2\n"},
+	}
+	for _, tp := range tests {
 		t.Run(tp.description, func(t *testing.T) {
-			gh := newGrepHandler([]byte(tp.line), tp.pattern)
-			ok := gh.ExtractPatterns()
-			if !ok {
-				t.Fatal("no pattern found")
-			} else {
-				actual, err := gh.matchPatterns()
-				if err != nil {
-					t.Fatalf("error returned: %s", err)
-				} else if actual != tp.expected {
-					t.Fatalf("failed to match %s with pattern: %s", tp.line, tp.pattern)
-				}
+			if output := searchOutput(t, tp.args); output != tp.expected {
+				t.Fatalf("wrong output: got %q expected: %q", output, tp.expected)
 			}
 		})
 	}
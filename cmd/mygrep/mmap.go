@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"os"
+
+	"github.com/codecrafters-io/grep-starter-go/internal/mmap"
+)
+
+// the smallest file worth mapping in memory, a smaller file is read in a single chunk anyway
+const mmapMinSize = 64 * 1024
+
+// the lines of an input, read by a regex.LineScanner or found in a memory mapped file
+type lineSource interface {
+	Scan() bool
+	Line() []byte
+	LineNumber() int
+	Offset() int64
+	Err() error
+}
+
+// map a file in memory for --mmap, returns nil when the file is read in chunks instead:
+// pipes and special files can't be mapped, small files are not worth it and some systems don't support it
+func mapFile(f *os.File) []byte {
+	info, err := f.Stat()
+	if err != nil || !info.Mode().IsRegular() || info.Size() < mmapMinSize || info.Size() > math.MaxInt {
+		return nil
+	}
+	data, err := mmap.Map(f, int(info.Size()))
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// the lines of a memory mapped file that matter to the search. The whole buffer is searched for the next line
+// with a match, then only this line and its context lines are yielded; the lines in between are skipped
+// without being split or matched one by one
+type bufferScanner struct {
+	re            matcher
+	data          []byte
+	before, after int //number of context lines around a line with a match
+	pos           int //offset of the next line
+	pos_number    int //1-based number of the next line
+	match_start   int //offset of the last line found with a match, -1 before the first one
+	remaining     int //number of lines left to yield after the line with a match
+	line          []byte
+	number        int
+	offset        int
+}
+
+func newBufferScanner(re matcher, data []byte, opts *options) *bufferScanner {
+	return &bufferScanner{re: re, data: data, before: opts.before_context, after: opts.after_context, pos_number: 1, match_start: -1}
+}
+
+func (bs *bufferScanner) Scan() bool {
+	if bs.pos >= len(bs.data) {
+		return false
+	}
+	if bs.pos > bs.match_start && bs.remaining == 0 {
+		loc := bs.re.FindLineIndex(bs.data[bs.pos:])
+		if loc == nil {
+			bs.pos = len(bs.data)
+			return false
+		}
+		bs.match_start = bs.pos + loc[0]
+		//go back to the first line of the before context
+		start := bs.match_start
+		for x := 0; x < bs.before && start > bs.pos; x++ {
+			start = bs.pos + bytes.LastIndexByte(bs.data[bs.pos:start-1], '\n') + 1
+		}
+		bs.pos_number += bytes.Count(bs.data[bs.pos:start], []byte{'\n'})
+		bs.pos = start
+	}
+
+	end := bytes.IndexByte(bs.data[bs.pos:], '\n')
+	if end < 0 {
+		end = len(bs.data)
+	} else {
+		end += bs.pos
+	}
+	bs.line, bs.number, bs.offset = bs.data[bs.pos:end], bs.pos_number, bs.pos
+	bs.pos, bs.pos_number = end+1, bs.pos_number+1
+	if bs.offset == bs.match_start {
+		bs.remaining = bs.after
+	} else if bs.offset > bs.match_start {
+		bs.remaining--
+		//a match in the after context has its own after context
+		if bs.after > 0 && bs.re.Match(bs.line) {
+			bs.remaining = bs.after
+		}
+	}
+	return true
+}
+
+func (bs *bufferScanner) Line() []byte {
+	return bs.line
+}
+
+func (bs *bufferScanner) LineNumber() int {
+	return bs.number
+}
+
+func (bs *bufferScanner) Offset() int64 {
+	return int64(bs.offset)
+}
+
+// the whole file is in memory, there is no read error
+func (bs *bufferScanner) Err() error {
+	return nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"slices"
+)
+
+// a lineSource that found the matches of its lines itself, grepLines then uses them rather than matching each line
+type matchedSource interface {
+	lineSource
+	Matches() [][]int //the submatch offsets of the matches of the current line, relative to the line
+}
+
+// the submatch offsets of every match of the current line of a source
+func findAllMatches(re matcher, lines lineSource, line []byte) [][]int {
+	if ms, ok := lines.(matchedSource); ok {
+		return ms.Matches()
+	}
+	return re.FindAllSubmatchIndex(line, -1)
+}
+
+// the offsets of the first match of the current line of a source, nil if there is none
+func findFirstMatch(re matcher, lines lineSource, line []byte) []int {
+	if ms, ok := lines.(matchedSource); ok {
+		if matches := ms.Matches(); len(matches) > 0 {
+			return matches[0][:2]
+		}
+		return nil
+	}
+	return re.FindIndex(line)
+}
+
+// the lines of an input searched as a whole for -U, so that a match can span several lines. The lines touched
+// by a match are yielded together as a single line, with the number and the offset of the first one, and the
+// matches overlapping the same lines are merged. The other lines are yielded one by one, without a match
+type multilineScanner struct {
+	re         matcher
+	data       []byte
+	matches    [][]int //the matches in the whole input, found on the first call to Scan
+	searched   bool
+	pos        int //offset of the next line
+	pos_number int //1-based number of the next line
+	line       []byte
+	line_match [][]int //the matches of the current line
+	number     int
+	offset     int
+}
+
+func newMultilineScanner(re matcher, data []byte) *multilineScanner {
+	return &multilineScanner{re: re, data: data, pos_number: 1}
+}
+
+func (ms *multilineScanner) Scan() bool {
+	if ms.pos >= len(ms.data) {
+		return false
+	}
+	if !ms.searched {
+		ms.matches, ms.searched = ms.re.FindAllSubmatchIndex(ms.data, -1), true
+	}
+	start := ms.pos
+	end := lineEnd(ms.data, start)
+	ms.line_match = ms.line_match[:0]
+	//take the matches starting on the line, extending it to the last line they touch
+	for len(ms.matches) > 0 && ms.matches[0][0] <= end {
+		match := ms.matches[0]
+		end = max(end, lineEnd(ms.data, max(match[0], match[1]-1)))
+		ms.line_match = append(ms.line_match, relativeMatch(match, start))
+		ms.matches = ms.matches[1:]
+	}
+	ms.line, ms.number, ms.offset = ms.data[start:end], ms.pos_number, start
+	ms.pos_number += 1 + bytes.Count(ms.line, []byte{'\n'})
+	ms.pos = end + 1
+	return true
+}
+
+// the offset of the newline ending the line containing the offset x, len(data) for the last line without a newline
+func lineEnd(data []byte, x int) int {
+	if x >= len(data) {
+		return len(data)
+	}
+	end := bytes.IndexByte(data[x:], '\n')
+	if end < 0 {
+		return len(data)
+	}
+	return x + end
+}
+
+// the submatch offsets of a match relative to the line starting at offset start, -1 stays -1.
+// The index of the pattern ending the offsets of a pattern set is kept as is
+func relativeMatch(match []int, start int) []int {
+	loc := slices.Clone(match)
+	for x := range loc[:len(loc)&^1] {
+		if loc[x] >= 0 {
+			loc[x] -= start
+		}
+	}
+	return loc
+}
+
+func (ms *multilineScanner) Line() []byte {
+	return ms.line
+}
+
+func (ms *multilineScanner) Matches() [][]int {
+	return ms.line_match
+}
+
+func (ms *multilineScanner) LineNumber() int {
+	return ms.number
+}
+
+func (ms *multilineScanner) Offset() int64 {
+	return int64(ms.offset)
+}
+
+// the whole input is in memory, there is no read error
+func (ms *multilineScanner) Err() error {
+	return nil
+}
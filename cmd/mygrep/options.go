@@ -0,0 +1,402 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var errMissingPattern = errors.New("missing pattern")
+
+// the short options followed by a value, -A 2 or -A2
+const shortOptionsWithValue = "ABCefm"
+
+// the long options followed by a value, --context 2 or --context=2
+var longOptionsWithValue = map[string]bool{
+	"after-context":   true,
+	"before-context":  true,
+	"binary-files":    true,
+	"column-unit":     true,
+	"context":         true,
+	"exclude":         true,
+	"file":            true,
+	"format":          true,
+	"group-separator": true,
+	"include":         true,
+	"max-count":       true,
+	"regexp":          true,
+	"replace":         true,
+}
+
+// the command line options
+type options struct {
+	patterns           []string       //the raw patterns, the first operand unless given by -e or -f
+	has_patterns       bool           //-e or -f gave the patterns, the operands are all files
+	files              []string       //the files to search, stdin when empty
+	only_matching      bool           //-o print only the matched parts of a line
+	line_number        bool           //-n prefix each line with its line number
+	byte_offset        bool           //-b prefix each line with its byte offset
+	column             bool           //--column prefix each line with the column of the first match
+	word_regexp        bool           //-w the match has to be a whole word
+	line_regexp        bool           //-x the match has to be the whole line
+	max_count          int            //-m stop after this number of selected lines in a file, -1 for no limit
+	quiet              bool           //-q print nothing, exit on the first selected line
+	no_messages        bool           //-s don't print the errors about files
+	with_filename      bool           //-H prefix each line with its file name
+	no_filename        bool           //-h never prefix lines with their file name
+	after_context      int            //-A number of lines to print after a selected line
+	before_context     int            //-B number of lines to print before a selected line
+	group_separator    string         //printed between groups of lines that are not adjacent, when printing context
+	no_group_separator bool           //--no-group-separator
+	color              string         //--color when to color the output: never, always or auto
+	replace            []byte         //--replace the template replacing the matches in the printed lines
+	has_replace        bool           //the replace template is set, an empty template deletes the matches
+	in_place           bool           //--in-place rewrite the files with the matches replaced instead of printing them
+	backup_suffix      string         //--in-place=SUFFIX keep the original of a rewritten file with this suffix
+	split              bool           //--split print the fields of the selected lines, separated by the matches, one per line
+	mmap               bool           //--mmap search the large regular files through a memory mapping
+	binary_files       string         //--binary-files how to search the binary files: binary, text or without-match
+	recursive          bool           //-r search the files in the directories, recursively
+	no_ignore          bool           //--no-ignore don't skip the files matched by .gitignore, .ignore and .git/info/exclude
+	hidden             bool           //--hidden don't skip the hidden files and directories
+	unrestricted       int            //number of -u, each one relaxes the filtering of -r a bit more
+	search_zip         bool           //-z search the content of the compressed files and of the zip and tar archives
+	include            []string       //--include only search the files matching one of these globs
+	exclude            []string       //--exclude skip the files matching one of these globs
+	null_data          bool           //--null-data the lines of the input and of the output end with a NUL byte instead of a newline
+	null               bool           //-Z print a NUL byte after the file names instead of a separator
+	multiline          bool           //-U match the whole input at once, a match can span several lines
+	json               bool           //--json print the lines as JSON messages, in the format of ripgrep
+	format             string         //--format the format of the output: sarif, emacs or a template, empty for the lines of grep
+	template           []templatePart //--format a template printed for each match, when the format has a field between braces
+	vimgrep            bool           //--vimgrep print file:line:column:line for each match
+	column_unit        string         //--column-unit count the columns in bytes or in characters: byte or char
+	explain            string         //--explain print the syntax tree of the patterns instead of searching: text or json
+}
+
+// parse the command line arguments, options can be grouped (-oE) and can appear before or after the pattern
+func parseArgs(args []string) (*options, error) {
+	opts := &options{group_separator: "--", color: "never", max_count: -1, binary_files: "binary", column_unit: "byte"}
+	operands := make([]string, 0)
+	for x := 0; x < len(args); x++ {
+		arg := args[x]
+		if arg == "--" { //everything after -- is an operand
+			operands = append(operands, args[x+1:]...)
+			break
+		} else if strings.HasPrefix(arg, "--") {
+			name, value, has_value := strings.Cut(arg[2:], "=")
+			if longOptionsWithValue[name] && !has_value {
+				if x+1 >= len(args) {
+					return nil, fmt.Errorf("option '--%s' requires an argument", name)
+				}
+				x++
+				value = args[x]
+			}
+			if err := opts.parseLongOption(name, value); err != nil {
+				return nil, err
+			}
+		} else if len(arg) > 1 && arg[0] == '-' {
+			for y, c := range arg[1:] {
+				if !strings.ContainsRune(shortOptionsWithValue, c) {
+					if err := opts.parseShortOption(c, ""); err != nil {
+						return nil, err
+					}
+					continue
+				}
+				//the value is the rest of the argument, or the next argument
+				value := arg[y+2:]
+				if value == "" {
+					if x+1 >= len(args) {
+						return nil, fmt.Errorf("option requires an argument -- '%c'", c)
+					}
+					x++
+					value = args[x]
+				}
+				if err := opts.parseShortOption(c, value); err != nil {
+					return nil, err
+				}
+				break
+			}
+		} else {
+			operands = append(operands, arg)
+		}
+	}
+	if !opts.has_patterns {
+		if len(operands) < 1 {
+			return nil, errMissingPattern
+		}
+		opts.patterns, operands = operands[:1], operands[1:]
+	}
+	opts.files = operands
+	if opts.in_place && !opts.has_replace {
+		return nil, errors.New("--in-place requires --replace")
+	} else if opts.split && (opts.only_matching || opts.has_replace) {
+		return nil, errors.New("--split can't be used with -o or --replace")
+	} else if opts.multiline && (opts.in_place || opts.null_data) {
+		return nil, errors.New("--multiline can't be used with --in-place or --null-data")
+	} else if opts.json && opts.format != "" {
+		return nil, errors.New("--json can't be used with --format")
+	} else if opts.vimgrep && (opts.json || opts.format != "") {
+		return nil, errors.New("--vimgrep can't be used with --json or --format")
+	} else if (opts.json || opts.format == "sarif" || opts.template != nil) && (opts.only_matching || opts.has_replace || opts.split) {
+		return nil, errors.New("--json, --format=sarif and --format templates can't be used with -o, --replace or --split")
+	}
+	//like grep, -r without a file searches the working directory
+	if opts.recursive && len(opts.files) == 0 {
+		opts.files = []string{"."}
+	}
+	//like grep, file names are printed by default when searching more than one file, as in an archive
+	if (len(opts.files) > 1 || opts.recursive || opts.search_zip && slices.ContainsFunc(opts.files, isArchive)) && !opts.no_filename {
+		opts.with_filename = true
+	}
+	return opts, nil
+}
+
+func (opts *options) parseShortOption(c rune, value string) error {
+	switch c {
+	case 'E': //extended regular expressions are the only ones we support
+	case 'e':
+		opts.patterns, opts.has_patterns = append(opts.patterns, value), true
+	case 'f':
+		patterns, err := readPatternFile(value)
+		if err != nil {
+			return err
+		}
+		opts.patterns, opts.has_patterns = append(opts.patterns, patterns...), true
+	case 'o':
+		opts.only_matching = true
+	case 'n':
+		opts.line_number = true
+	case 'b':
+		opts.byte_offset = true
+	case 'w':
+		opts.word_regexp = true
+	case 'x':
+		opts.line_regexp = true
+	case 'm':
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid max count: %s", value)
+		}
+		//like grep, a negative count means no limit
+		opts.max_count = max(n, -1)
+	case 'q':
+		opts.quiet = true
+	case 's':
+		opts.no_messages = true
+	case 'H':
+		opts.with_filename, opts.no_filename = true, false
+	case 'h':
+		opts.with_filename, opts.no_filename = false, true
+	case 'a':
+		opts.binary_files = "text"
+	case 'I':
+		opts.binary_files = "without-match"
+	case 'z':
+		opts.search_zip = true
+	case 'Z':
+		opts.null = true
+	case 'U':
+		opts.multiline = true
+	case 'r':
+		opts.recursive = true
+	case 'u': //-u is --no-ignore, -uu adds --hidden and -uuu adds --text
+		opts.unrestricted++
+		opts.no_ignore = true
+		opts.hidden = opts.hidden || opts.unrestricted >= 2
+		if opts.unrestricted >= 3 {
+			opts.binary_files = "text"
+		}
+	case 'A':
+		return parseContextLength(value, &opts.after_context)
+	case 'B':
+		return parseContextLength(value, &opts.before_context)
+	case 'C':
+		if err := parseContextLength(value, &opts.after_context); err != nil {
+			return err
+		}
+		opts.before_context = opts.after_context
+	default:
+		return fmt.Errorf("invalid option -- '%c'", c)
+	}
+	return nil
+}
+
+func (opts *options) parseLongOption(name, value string) error {
+	switch name {
+	case "extended-regexp":
+	case "only-matching":
+		opts.only_matching = true
+	case "line-number":
+		opts.line_number = true
+	case "byte-offset":
+		opts.byte_offset = true
+	case "column":
+		opts.column = true
+	case "word-regexp":
+		opts.word_regexp = true
+	case "line-regexp":
+		opts.line_regexp = true
+	case "max-count":
+		return opts.parseShortOption('m', value)
+	case "regexp":
+		return opts.parseShortOption('e', value)
+	case "file":
+		return opts.parseShortOption('f', value)
+	case "quiet", "silent":
+		opts.quiet = true
+	case "no-messages":
+		opts.no_messages = true
+	case "with-filename":
+		opts.with_filename, opts.no_filename = true, false
+	case "no-filename":
+		opts.with_filename, opts.no_filename = false, true
+	case "after-context":
+		return opts.parseShortOption('A', value)
+	case "before-context":
+		return opts.parseShortOption('B', value)
+	case "context":
+		return opts.parseShortOption('C', value)
+	case "group-separator":
+		opts.group_separator, opts.no_group_separator = value, false
+	case "no-group-separator":
+		opts.no_group_separator = true
+	case "color", "colour":
+		if value == "" {
+			value = "auto"
+		}
+		if value != "always" && value != "never" && value != "auto" {
+			return fmt.Errorf("invalid argument '%s' for '--color'", value)
+		}
+		opts.color = value
+	case "replace":
+		opts.replace, opts.has_replace = []byte(value), true
+	case "in-place":
+		opts.in_place, opts.backup_suffix = true, value
+	case "split":
+		opts.split = true
+	case "text":
+		opts.binary_files = "text"
+	case "binary-files":
+		if value != "binary" && value != "text" && value != "without-match" {
+			return fmt.Errorf("invalid argument '%s' for '--binary-files'", value)
+		}
+		opts.binary_files = value
+	case "search-zip":
+		opts.search_zip = true
+	case "null-data":
+		opts.null_data = true
+	case "null":
+		opts.null = true
+	case "multiline":
+		opts.multiline = true
+	case "json":
+		opts.json = true
+	case "explain":
+		if value == "" {
+			value = "text"
+		}
+		if value != "text" && value != "json" {
+			return fmt.Errorf("invalid argument '%s' for '--explain'", value)
+		}
+		opts.explain = value
+	case "format":
+		if strings.ContainsRune(value, '{') {
+			template, err := parseTemplate(value)
+			if err != nil {
+				return err
+			}
+			opts.format, opts.template = value, template
+			return nil
+		}
+		if value != "sarif" && value != "emacs" {
+			return fmt.Errorf("invalid argument '%s' for '--format'", value)
+		}
+		opts.format, opts.template = value, nil
+	case "vimgrep":
+		opts.vimgrep = true
+	case "column-unit":
+		if value != "byte" && value != "char" {
+			return fmt.Errorf("invalid argument '%s' for '--column-unit'", value)
+		}
+		opts.column_unit = value
+	case "include":
+		opts.include = append(opts.include, value)
+	case "exclude":
+		opts.exclude = append(opts.exclude, value)
+	case "recursive":
+		opts.recursive = true
+	case "no-ignore":
+		opts.no_ignore = true
+	case "hidden":
+		opts.hidden = true
+	case "unrestricted":
+		return opts.parseShortOption('u', "")
+	case "mmap":
+		opts.mmap = true
+	case "no-mmap":
+		opts.mmap = false
+	default:
+		return fmt.Errorf("unrecognized option '--%s'", name)
+	}
+	return nil
+}
+
+// parse the number of context lines of -A -B -C
+func parseContextLength(value string, length *int) error {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return fmt.Errorf("%s: invalid context length argument", value)
+	}
+	*length = n
+	return nil
+}
+
+// check if a file, or a member of an archive, is searched according to --include and --exclude
+func (opts *options) isSelectedFile(filename string) bool {
+	return !opts.isExcluded(filename) && (len(opts.include) == 0 || matchesAnyGlob(opts.include, filename))
+}
+
+func (opts *options) isExcluded(filename string) bool {
+	return matchesAnyGlob(opts.exclude, filename)
+}
+
+// like grep, a glob matches a name suffix: the whole name or any part of it after a slash, so *.txt matches dir/a.txt
+func matchesAnyGlob(globs []string, filename string) bool {
+	name := filepath.ToSlash(filename)
+	for _, glob := range globs {
+		for suffix := name; ; {
+			if ok, _ := path.Match(glob, suffix); ok {
+				return true
+			}
+			x := strings.IndexByte(suffix, '/')
+			if x < 0 {
+				break
+			}
+			suffix = suffix[x+1:]
+		}
+	}
+	return false
+}
+
+// the byte ending the lines of the input and of the output
+func (opts *options) terminator() byte {
+	if opts.null_data {
+		return 0
+	}
+	return '\n'
+}
+
+// check if a line is printed for each match, with the location of the match: --vimgrep, --format=emacs and templates
+func (opts *options) perMatch() bool {
+	return opts.vimgrep || opts.format == "emacs" || opts.template != nil
+}
+
+// check if context lines are printed around the selected lines
+func (opts *options) hasContext() bool {
+	return opts.after_context > 0 || opts.before_context > 0
+}
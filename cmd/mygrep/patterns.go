@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+
+	"github.com/codecrafters-io/grep-starter-go/regex"
+)
+
+// the compiled patterns of the command line: a *regex.Regexp for a single pattern,
+// a *patternSet when -e and -f give several of them
+type matcher interface {
+	Match(b []byte) bool
+	FindIndex(b []byte) []int
+	FindAllSubmatchIndex(b []byte, n int) [][]int
+	FindLineIndex(b []byte) []int
+	Split(s string, n int) []string
+	Expand(dst, template, src []byte, match []int) []byte
+}
+
+// compile each pattern of the options, a *regex.SyntaxError is returned for the first invalid one
+func compilePatterns(opts *options) ([]*regex.Regexp, error) {
+	//with --null-data a newline is an ordinary byte of the lines
+	ro := regex.Options{Word: opts.word_regexp, Line: opts.line_regexp, DotNewline: opts.null_data}
+	patterns := make([]*regex.Regexp, 0, len(opts.patterns))
+	for _, pattern := range opts.patterns {
+		re, err := regex.Compile(pattern, ro)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, re)
+	}
+	return patterns, nil
+}
+
+// compile the patterns of the options into a matcher, a *regex.SyntaxError is returned if a pattern is invalid
+func compilePattern(opts *options) (matcher, error) {
+	patterns, err := compilePatterns(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTemplate(opts.template, patterns); err != nil {
+		return nil, err
+	}
+	if len(patterns) == 1 {
+		return patterns[0], nil
+	}
+	return &patternSet{patterns: patterns}, nil
+}
+
+// the patterns of -e and -f, a line is selected when any of them matches. The matches are those of the pattern
+// matching first in the line, the longest one when several patterns match at the same offset, along with
+// the capture groups of that pattern and then the index of that pattern. Without any pattern nothing matches,
+// as with an empty -f file
+type patternSet struct {
+	patterns []*regex.Regexp
+}
+
+func (ps *patternSet) Match(b []byte) bool {
+	for _, re := range ps.patterns {
+		if re.Match(b) {
+			return true
+		}
+	}
+	return false
+}
+
+func (ps *patternSet) FindIndex(b []byte) []int {
+	matches := ps.FindAllSubmatchIndex(b, 1)
+	if matches == nil {
+		return nil
+	}
+	return matches[0][:2]
+}
+
+// the successive non-overlapping matches of the patterns, at most n of them, every match if n < 0.
+// After each match every pattern is searched again from its end, so a match of a pattern can start
+// in the middle of a match it found that was overlapped by the match of another pattern.
+// Like Go's regexp, an empty match right after the previous match is not reported
+func (ps *patternSet) FindAllSubmatchIndex(b []byte, n int) [][]int {
+	var matches [][]int
+	//the next match of each pattern, it is searched again once the search goes past its start
+	next := make([][]int, len(ps.patterns))
+	searched := make([]bool, len(ps.patterns))
+	prev_end := -1
+	for pos := 0; pos <= len(b) && (n < 0 || len(matches) < n); {
+		var first []int
+		for x, re := range ps.patterns {
+			if !searched[x] || next[x] != nil && next[x][0] < pos {
+				next[x], searched[x] = re.FindSubmatchIndexFrom(b, pos), true
+			}
+			if m := next[x]; m != nil && (first == nil || m[0] < first[0] || m[0] == first[0] && m[1] > first[1]) {
+				first = append(m, x)
+			}
+		}
+		if first == nil {
+			break
+		}
+		if first[0] < first[1] {
+			matches = append(matches, first)
+			pos = first[1]
+		} else {
+			if first[0] != prev_end {
+				matches = append(matches, first)
+			}
+			pos = first[1] + 1
+		}
+		prev_end = first[1]
+	}
+	return matches
+}
+
+// the offsets of the first line containing a match of any pattern
+func (ps *patternSet) FindLineIndex(b []byte) []int {
+	var first []int
+	for _, re := range ps.patterns {
+		if loc := re.FindLineIndex(b); loc != nil && (first == nil || loc[0] < first[0]) {
+			first = loc
+		}
+	}
+	return first
+}
+
+// split s around the matches of the patterns, like regex.Regexp.Split
+func (ps *patternSet) Split(s string, n int) []string {
+	if n != 0 && len(s) == 0 {
+		return []string{""}
+	}
+	return regex.SplitMatches(s, ps.FindAllSubmatchIndex([]byte(s), n), n)
+}
+
+// expand the template with the capture groups of the pattern that found the match
+func (ps *patternSet) Expand(dst, template, src []byte, match []int) []byte {
+	if x := matchingPattern(ps.patterns, match); x >= 0 {
+		return ps.patterns[x].Expand(dst, template, src, match[:len(match)-1])
+	}
+	return dst
+}
+
+// the index of the pattern that found a match, given its submatch offsets: those of a pattern set end with it.
+// -1 if it isn't known
+func matchingPattern(patterns []*regex.Regexp, match []int) int {
+	if len(match)%2 == 1 {
+		return match[len(match)-1]
+	} else if len(patterns) == 1 {
+		return 0
+	}
+	return -1
+}
+
+// read the patterns of -f, one per line. - is stdin
+func readPatternFile(filename string) ([]string, error) {
+	var data []byte
+	var err error
+	if filename == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(filename)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	lines := bytes.Split(bytes.TrimSuffix(data, []byte{'\n'}), []byte{'\n'})
+	patterns := make([]string, len(lines))
+	for x, line := range lines {
+		patterns[x] = string(line)
+	}
+	return patterns, nil
+}
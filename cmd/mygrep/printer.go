@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/codecrafters-io/grep-starter-go/regex"
+)
+
+// the separator between the prefix fields and the text of a selected line
+const matchSeparator = ':'
+
+// a line, or part of a line with -o, to print
+type outputLine struct {
+	filename    string
+	line_number int
+	column      int //1-based column of the first match, 0 for context lines which don't have one
+	offset      int //byte offset of text in the file
+	text        []byte
+	matches     [][]int //[start, end] byte offsets of the matches in text, to highlight them
+}
+
+// write the selected lines, or parts of them, along with the prefix asked by the options
+type printer struct {
+	out      *bufio.Writer
+	opts     *options
+	colors   *colors         //nil when the output is not colored
+	printed  bool            //true once a line was printed
+	begun    bool            //--json the begin message of the current input was printed
+	stats    searchStats     //--json the statistics of the current input
+	total    searchStats     //--json the statistics of the inputs searched so far
+	patterns []*regex.Regexp //--format=sarif and templates the patterns, one rule each for sarif
+	results  []sarifResult   //--format=sarif the results of the matches found so far
+	output   *fileOutput     //the output of the file being searched, nil when printing straight to out
+}
+
+func newPrinter(out *bufio.Writer, opts *options) *printer {
+	return &printer{out: out, opts: opts}
+}
+
+// write out the lines printed so far, up to the shared writer when the file is at the head of the queue
+func (p *printer) flush() {
+	p.out.Flush()
+	if p.output != nil {
+		p.output.Flush()
+	}
+}
+
+// print a line prefixed by its file name, line number, column and byte offset,
+// sep tells a selected line (:) from a context line (-)
+func (p *printer) printLine(ol outputLine, sep byte) {
+	p.printed = true
+	if p.opts.json {
+		p.printJSONLine(ol, sep)
+		return
+	} else if p.opts.format == "sarif" {
+		//the results are printed at the end, the context lines are not part of them
+		if sep == matchSeparator {
+			p.addSARIFResults(ol)
+		}
+		return
+	} else if p.opts.template != nil {
+		if sep == matchSeparator {
+			p.printTemplate(ol)
+		}
+		return
+	} else if p.opts.perMatch() {
+		if sep == matchSeparator {
+			p.printMatchLines(ol)
+		}
+		return
+	}
+	if p.opts.with_filename && p.opts.null {
+		//-Z the file name ends with a NUL byte, for xargs -0
+		p.printColored(ol.filename, p.color().filename)
+		p.out.WriteByte(0)
+	} else if p.opts.with_filename {
+		p.printField(ol.filename, p.color().filename, sep)
+	}
+	if p.opts.line_number {
+		p.printField(strconv.Itoa(ol.line_number), p.color().line_number, sep)
+	}
+	if p.opts.column && ol.column > 0 {
+		p.printField(strconv.Itoa(ol.column), p.color().line_number, sep)
+	}
+	if p.opts.byte_offset {
+		p.printField(strconv.Itoa(ol.offset), p.color().byte_offset, sep)
+	}
+	if sep == contextSeparator {
+		p.printText(ol.text, ol.matches, p.color().context_line, p.color().context_match)
+	} else {
+		p.printText(ol.text, ol.matches, p.color().selected_line, p.color().selected_match)
+	}
+	p.out.WriteByte(p.opts.terminator())
+}
+
+func (p *printer) printField(field, sgr string, sep byte) {
+	p.printColored(field, sgr)
+	p.printColored(string(sep), p.color().separator)
+}
+
+// print the text of a line, with its matches highlighted when the output is colored
+func (p *printer) printText(text []byte, matches [][]int, line_sgr, match_sgr string) {
+	if p.colors == nil {
+		p.out.Write(text)
+		return
+	}
+	pos := 0
+	for _, match := range matches {
+		if match[0] == match[1] {
+			continue
+		}
+		p.printColored(string(text[pos:match[0]]), line_sgr)
+		p.printColored(string(text[match[0]:match[1]]), match_sgr)
+		pos = match[1]
+	}
+	p.printColored(string(text[pos:]), line_sgr)
+}
+
+// print a line for each match of a selected line for --vimgrep and --format=emacs, so that editors can jump
+// to the matches: the file name, the line number and the column of the match, then the line containing it.
+// A line with several matches is printed once for each. With -o the line is the match itself
+func (p *printer) printMatchLines(ol outputLine) {
+	printMatch := func(line_number, column int, text []byte, matches [][]int) {
+		p.printField(ol.filename, p.color().filename, matchSeparator)
+		p.printField(strconv.Itoa(line_number), p.color().line_number, matchSeparator)
+		p.printField(strconv.Itoa(column), p.color().line_number, matchSeparator)
+		if p.opts.format == "emacs" { //the GNU format of compilation messages, file:line:column: message
+			p.out.WriteByte(' ')
+		}
+		p.printText(text, matches, p.color().selected_line, p.color().selected_match)
+		p.out.WriteByte(p.opts.terminator())
+	}
+	if p.opts.only_matching {
+		printMatch(ol.line_number, ol.column, ol.text, ol.matches)
+		return
+	}
+	for _, match := range ol.matches {
+		line, line_number, start := matchLine(ol, match)
+		printMatch(line_number, p.columnAt(line, match[0]-start), line, [][]int{{match[0] - start, min(match[1]-start, len(line))}})
+	}
+}
+
+// the line of a selected text containing the start of a match, along with its number and its offset in the text.
+// It is the text itself unless -U made it span several lines
+func matchLine(ol outputLine, match []int) ([]byte, int, int) {
+	start := bytes.LastIndexByte(ol.text[:match[0]], '\n') + 1
+	end := len(ol.text)
+	if x := bytes.IndexByte(ol.text[match[0]:], '\n'); x >= 0 {
+		end = match[0] + x
+	}
+	return ol.text[start:end], ol.line_number + bytes.Count(ol.text[:start], []byte{'\n'}), start
+}
+
+// the 1-based column of an offset of a line, in bytes or in characters according to --column-unit
+func (p *printer) columnAt(line []byte, offset int) int {
+	if p.opts.column_unit == "char" {
+		return utf8.RuneCount(line[:offset]) + 1
+	}
+	return offset + 1
+}
+
+// print s surrounded by the SGR sequence when the output is colored
+func (p *printer) printColored(s, sgr string) {
+	if p.colors == nil || sgr == "" || s == "" {
+		p.out.WriteString(s)
+		return
+	}
+	p.out.WriteString(p.colors.start(sgr))
+	p.out.WriteString(s)
+	p.out.WriteString(p.colors.end())
+}
+
+// the colors of the output, empty when the output is not colored
+func (p *printer) color() *colors {
+	if p.colors == nil {
+		return &noColors
+	}
+	return p.colors
+}
+
+// print the separator between groups of lines that are not adjacent
+func (p *printer) printGroupSeparator() {
+	if p.opts.no_group_separator || p.opts.json || p.opts.format != "" || p.opts.vimgrep {
+		return
+	}
+	p.printColored(p.opts.group_separator, p.color().separator)
+	p.out.WriteByte(p.opts.terminator())
+}
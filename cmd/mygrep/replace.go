@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/codecrafters-io/grep-starter-go/regex"
+)
+
+// replace the matches of a line by the template, matches are the submatch offsets of the matches in line.
+// Returns the new line along with the [start, end] offsets of the replacements in it, to highlight them
+func replaceMatches(re matcher, line []byte, matches [][]int, template []byte) ([]byte, [][]int) {
+	text := make([]byte, 0, len(line))
+	replacements := make([][]int, 0, len(matches))
+	last := 0
+	for _, match := range matches {
+		text = append(text, line[last:match[0]]...)
+		start := len(text)
+		text = re.Expand(text, template, line, match)
+		replacements = append(replacements, []int{start, len(text)})
+		last = match[1]
+	}
+	return append(text, line[last:]...), replacements
+}
+
+// replace the matches in every line of a file and write it back, like sed -i.
+// The file is replaced atomically by a new one, the original is kept with the --in-place suffix when there is one.
+// Returns true if at least one line had a match, the file is left untouched otherwise
+func editFile(re matcher, filename string, opts *options) (bool, error) {
+	if filename == "-" {
+		return false, errors.New("cannot edit the standard input in place")
+	}
+	input, err := os.Open(filename)
+	if err != nil {
+		return false, err
+	}
+	defer input.Close()
+	info, err := input.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	//the new content is written next to the file so that it can be renamed over it
+	tmp, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".mygrep-*")
+	if err != nil {
+		return false, err
+	}
+	//once renamed there is nothing left to remove
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	out := bufio.NewWriter(tmp)
+	selected, err := replaceLines(re, input, out, opts.replace, opts.terminator())
+	if err != nil {
+		return selected, fmt.Errorf("%s: %w", filename, err)
+	} else if !selected {
+		return false, nil
+	}
+	if err := out.Flush(); err != nil {
+		return true, fmt.Errorf("write %s: %w", filename, err)
+	}
+	if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+		return true, err
+	}
+	if err := tmp.Sync(); err != nil {
+		return true, err
+	}
+	if err := tmp.Close(); err != nil {
+		return true, err
+	}
+
+	if opts.backup_suffix != "" {
+		if err := backupFile(filename, filename+opts.backup_suffix, info.Mode()); err != nil {
+			return true, err
+		}
+	}
+	return true, os.Rename(tmp.Name(), filename)
+}
+
+// copy the input to out with the matches of every line replaced, the lines end with terminator.
+// Returns true if at least one line had a match
+func replaceLines(re matcher, input io.Reader, out io.Writer, template []byte, terminator byte) (bool, error) {
+	selected := false
+	ls := regex.NewLineScanner(input)
+	ls.SetTerminator(terminator)
+	for ls.Scan() {
+		line := ls.Line()
+		if matches := re.FindAllSubmatchIndex(line, -1); len(matches) > 0 {
+			selected = true
+			line, _ = replaceMatches(re, line, matches, template)
+		}
+		if _, err := out.Write(line); err != nil {
+			return selected, err
+		}
+		if ls.Newline() {
+			if _, err := out.Write([]byte{terminator}); err != nil {
+				return selected, err
+			}
+		}
+	}
+	return selected, ls.Err()
+}
+
+// keep the original content of a file under the backup name
+func backupFile(filename, backup string, mode os.FileMode) error {
+	if err := os.Remove(backup); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	//a hard link is enough since the file itself is replaced rather than modified
+	if err := os.Link(filename, backup); err == nil {
+		return nil
+	}
+	input, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+	out, err := os.OpenFile(backup, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode.Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, input); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/codecrafters-io/grep-starter-go/regex"
+)
+
+// the SARIF 2.1.0 log of --format=sarif: a single run of mygrep with a rule for each pattern
+// and a result for each match of a pattern, printed once every file was searched
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool       sarifTool     `json:"tool"`
+	ColumnKind string        `json:"columnKind"`
+	Results    []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	Name             string       `json:"name"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// the lines are 1-based, the columns too and the end column is the one following the match
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
+}
+
+// the patterns of a matcher, one for each -e and -f pattern
+func matcherPatterns(re matcher) []*regex.Regexp {
+	switch m := re.(type) {
+	case *patternSet:
+		return m.patterns
+	case *regex.Regexp:
+		return []*regex.Regexp{m}
+	}
+	return nil
+}
+
+// the id of the rule of the pattern at index x
+func sarifRuleID(x int) string {
+	return fmt.Sprintf("pattern-%d", x+1)
+}
+
+// add a result for each match of each pattern in a selected line, ordered by position then by pattern
+func (p *printer) addSARIFResults(ol outputLine) {
+	first := len(p.results)
+	uri := sarifURI(ol.filename)
+	for x, re := range p.patterns {
+		for _, loc := range re.FindAllSubmatchIndex(ol.text, -1) {
+			//like -o, an empty match selects the line but isn't reported
+			if loc[0] == loc[1] {
+				continue
+			}
+			start_line, start_column := textPosition(ol.text, loc[0])
+			end_line, end_column := textPosition(ol.text, loc[1])
+			p.results = append(p.results, sarifResult{
+				RuleID:    sarifRuleID(x),
+				RuleIndex: x,
+				Level:     "warning",
+				Message:   sarifMessage{Text: fmt.Sprintf("%q matches the pattern %s", ol.text[loc[0]:loc[1]], re)},
+				Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: uri},
+					Region: sarifRegion{
+						StartLine:   ol.line_number + start_line,
+						StartColumn: start_column,
+						EndLine:     ol.line_number + end_line,
+						EndColumn:   end_column,
+					},
+				}}},
+			})
+		}
+	}
+	results := p.results[first:]
+	sort.SliceStable(results, func(a, b int) bool {
+		ra, rb := results[a].Locations[0].PhysicalLocation.Region, results[b].Locations[0].PhysicalLocation.Region
+		if ra.StartLine != rb.StartLine {
+			return ra.StartLine < rb.StartLine
+		}
+		return ra.StartColumn < rb.StartColumn
+	})
+}
+
+// the position of an offset of a text that can span several lines with -U: the number of newlines before it
+// and its 1-based column in Unicode code points
+func textPosition(text []byte, offset int) (line, column int) {
+	line = bytes.Count(text[:offset], []byte{'\n'})
+	line_start := bytes.LastIndexByte(text[:offset], '\n') + 1
+	return line, utf8.RuneCount(text[line_start:offset]) + 1
+}
+
+// the URI of a file for the artifact locations, relative paths stay relative
+func sarifURI(filename string) string {
+	u := url.URL{Path: filepath.ToSlash(filename)}
+	if filepath.IsAbs(filename) {
+		u.Scheme = "file"
+	}
+	return u.String()
+}
+
+// print the SARIF log with the results of every file
+func printSARIF(p *printer, patterns []*regex.Regexp, results []sarifResult) {
+	rules := make([]sarifRule, len(patterns))
+	for x, re := range patterns {
+		rules[x] = sarifRule{ID: sarifRuleID(x), Name: re.String(), ShortDescription: sarifMessage{Text: "Matches of the pattern " + re.String()}}
+	}
+	if results == nil {
+		results = []sarifResult{}
+	}
+	log := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool:       sarifTool{Driver: sarifDriver{Name: "mygrep", Rules: rules}},
+			ColumnKind: "unicodeCodePoints",
+			Results:    results,
+		}},
+	}
+	b, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		//the log only holds strings and numbers
+		panic(err)
+	}
+	p.out.Write(b)
+	p.out.WriteByte('\n')
+}
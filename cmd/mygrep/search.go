@@ -0,0 +1,298 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/codecrafters-io/grep-starter-go/internal/mmap"
+	"github.com/codecrafters-io/grep-starter-go/regex"
+)
+
+// the result of the search of a file
+type fileResult struct {
+	selected bool
+	stats    searchStats   //--json the statistics of the inputs of the file
+	results  []sarifResult //--format=sarif the results of the file
+	err      error
+}
+
+// a file to search, its result is sent on done once the search is over
+type searchJob struct {
+	filename string
+	output   *fileOutput
+	done     chan fileResult
+}
+
+// the output of a file. The file at the head of the queue writes straight to the shared writer so that its lines
+// are printed as they are found, a file searched ahead of its turn keeps its output until it reaches the head
+type fileOutput struct {
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	out    *bufio.Writer //the shared writer, nil until the file is at the head of the queue
+	before func()        //prints what comes before the first output of the file, the group separator between files
+	wrote  bool
+	done   bool //the search of the file is over
+}
+
+func (fo *fileOutput) Write(b []byte) (int, error) {
+	fo.mu.Lock()
+	defer fo.mu.Unlock()
+	if fo.out == nil {
+		return fo.buf.Write(b)
+	}
+	fo.start(len(b))
+	return fo.out.Write(b)
+}
+
+// call before ahead of the first n > 0 bytes of output
+func (fo *fileOutput) start(n int) {
+	if n > 0 && !fo.wrote {
+		fo.wrote = true
+		fo.before()
+	}
+}
+
+// hand the shared writer to the file once it is at the head of the queue, the output kept so far is written first
+func (fo *fileOutput) direct(out *bufio.Writer, before func()) {
+	fo.mu.Lock()
+	defer fo.mu.Unlock()
+	fo.out, fo.before = out, before
+	fo.start(fo.buf.Len())
+	out.Write(fo.buf.Bytes())
+	//a file still searched can be waiting for more input, what it found so far is printed right away
+	if fo.buf.Len() > 0 && !fo.done {
+		out.Flush()
+	}
+	fo.buf = bytes.Buffer{}
+}
+
+// to call once the search of the file is over
+func (fo *fileOutput) finish() {
+	fo.mu.Lock()
+	defer fo.mu.Unlock()
+	fo.done = true
+}
+
+// flush the shared writer if the file is at the head of the queue
+func (fo *fileOutput) Flush() {
+	fo.mu.Lock()
+	defer fo.mu.Unlock()
+	if fo.out != nil {
+		fo.out.Flush()
+	}
+}
+
+// a reader printing the lines found so far before each read, reading a pipe or a terminal can block for a while
+type flushingReader struct {
+	r io.Reader
+	p *printer
+}
+
+func (fr *flushingReader) Read(b []byte) (int, error) {
+	fr.p.flush()
+	return fr.r.Read(b)
+}
+
+// search the files of the options in parallel, the output of each file is written in the order of the files:
+// the file at the head of the queue prints as it goes and the files searched ahead of it wait for their turn.
+// With -r the directories are walked while the files found so far are searched.
+// report is called with the errors, in the order of the files too.
+// returns true if at least one line was selected
+func searchFiles(opts *options, re matcher, colors *colors, out *bufio.Writer, report func(err error)) bool {
+	start := time.Now()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	queue := make(chan *searchJob)
+	ordered := make(chan *searchJob, 64) //the jobs in the order of the files, for the output
+	go func() {
+		defer close(queue)
+		defer close(ordered)
+		walkFiles(opts, func(filename string, err error) bool {
+			job := &searchJob{filename: filename, output: &fileOutput{}, done: make(chan fileResult, 1)}
+			select {
+			case ordered <- job:
+			case <-ctx.Done():
+				return false
+			}
+			//a directory that couldn't be walked only has its error to report
+			if err != nil {
+				job.done <- fileResult{err: err}
+				return true
+			}
+			select {
+			case queue <- job:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+
+	//with -q, the first selected line stops the search of every file
+	var found atomic.Bool
+	for w := 0; w < runtime.GOMAXPROCS(0); w++ {
+		go func() {
+			for job := range queue {
+				res := searchFile(ctx, opts, re, colors, job.filename, job.output)
+				if res.selected && opts.quiet {
+					found.Store(true)
+					cancel()
+				}
+				job.done <- res
+			}
+		}()
+	}
+
+	p := newPrinter(out, opts)
+	p.colors = colors
+	selected := false
+	for job := range ordered {
+		//the group separator also separates the groups of lines of two files
+		job.output.direct(out, func() {
+			if p.printed && opts.hasContext() {
+				p.printGroupSeparator()
+			}
+			p.printed = true
+		})
+		var res fileResult
+		select {
+		case res = <-job.done:
+		case <-ctx.Done():
+			return found.Load()
+		}
+		if res.err != nil {
+			report(res.err)
+		}
+		selected = selected || res.selected
+		p.total.add(res.stats)
+		p.results = append(p.results, res.results...)
+	}
+	if opts.json && !opts.quiet {
+		p.printJSON("summary", jsonSummary{ElapsedTotal: newJSONDuration(time.Since(start)), Stats: p.total})
+	} else if opts.format == "sarif" && !opts.quiet {
+		printSARIF(p, matcherPatterns(re), p.results)
+	}
+	return selected
+}
+
+// search a file, - is stdin. The lines are printed to output so that files searched in parallel don't mix their lines
+func searchFile(ctx context.Context, opts *options, re matcher, colors *colors, filename string, output *fileOutput) fileResult {
+	//--in-place rewrites the file and prints nothing
+	if opts.in_place {
+		selected, err := editFile(re, filename, opts)
+		return fileResult{selected: selected, err: err}
+	}
+	out := bufio.NewWriter(output)
+	if opts.quiet {
+		out = bufio.NewWriter(io.Discard)
+	}
+	p := newPrinter(out, opts)
+	p.colors, p.output = colors, output
+	if opts.format == "sarif" || opts.template != nil {
+		p.patterns = matcherPatterns(re)
+	}
+
+	var selected bool
+	var err error
+	if kind := archiveKind(filename); opts.search_zip && kind != "" {
+		selected, err = searchArchive(ctx, re, filename, kind, p)
+	} else {
+		selected, err = searchPath(ctx, re, filename, p)
+	}
+	out.Flush()
+	output.finish()
+	return fileResult{selected: selected, stats: p.total, results: p.results, err: err}
+}
+
+// open and search a file, - is stdin
+func searchPath(ctx context.Context, re matcher, filename string, p *printer) (bool, error) {
+	input, err := openInput(filename)
+	if err != nil {
+		return false, err
+	}
+	defer input.Close()
+	if filename == "-" {
+		filename = stdinName
+	}
+	return searchInput(ctx, re, filename, input, p)
+}
+
+// search the content of a file or of an archive member, the errors are prefixed with the name of the input
+func searchInput(ctx context.Context, re matcher, filename string, input io.Reader, p *printer) (bool, error) {
+	var err error
+	var reader io.Reader = input
+	compressed := false
+	if p.opts.search_zip {
+		if reader, compressed, err = decompress(filename, input); err != nil {
+			return false, fmt.Errorf("%s: %w", filename, err)
+		}
+	}
+	start := time.Now()
+	p.beginInput()
+	counter := &countingReader{r: reader}
+	reader = counter
+	var lines lineSource
+	var head []byte //the first buffer of the file
+	var data []byte //the whole file when it is memory mapped
+	//the whole buffer search of a memory mapped file looks for newlines
+	if f, ok := input.(*os.File); ok && p.opts.mmap && !compressed && !p.opts.null_data {
+		if data = mapFile(f); data != nil {
+			defer mmap.Unmap(data)
+		}
+	}
+	switch {
+	case p.opts.multiline:
+		//a match can span any number of lines, the whole input is searched at once
+		if data == nil {
+			if data, err = io.ReadAll(reader); err != nil {
+				return false, fmt.Errorf("%s: %w", filename, err)
+			}
+		}
+		lines = newMultilineScanner(re, data)
+		head = data[:min(len(data), binaryPeekSize)]
+	case data != nil:
+		lines = newBufferScanner(re, data, p.opts)
+		head = data[:min(len(data), binaryPeekSize)]
+	default:
+		//the lines found so far are printed before waiting for more input, so that stdin streams
+		head, reader = readHead(&flushingReader{r: reader, p: p})
+		lines = newLineScanner(reader, p.opts)
+	}
+
+	//with --null-data the NUL bytes end the lines, they don't make a binary file
+	var selected bool
+	binary_offset := -1
+	if p.opts.binary_files == "text" || p.opts.null_data || !isBinary(head) {
+		selected, err = grepLines(ctx, re, filename, lines, p)
+	} else if p.opts.binary_files == "binary" {
+		binary_offset = bytes.IndexByte(head, 0)
+		selected, err = grepBinary(ctx, re, filename, lines, p)
+	}
+	if p.opts.json {
+		bytes_searched := counter.n
+		if data != nil {
+			bytes_searched = int64(len(data))
+		}
+		p.endInput(filename, selected, bytes_searched, time.Since(start), binary_offset)
+	}
+	if err != nil {
+		err = fmt.Errorf("%s: %w", filename, err)
+	}
+	return selected, err
+}
+
+// a scanner reading the lines of an input, they end with the terminator of the options
+func newLineScanner(input io.Reader, opts *options) *regex.LineScanner {
+	ls := regex.NewLineScanner(input)
+	ls.SetTerminator(opts.terminator())
+	return ls
+}
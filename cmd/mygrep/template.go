@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/grep-starter-go/regex"
+)
+
+// the fields of a --format template, replaced by the values of each match:
+//
+//	{path}     the name of the file
+//	{line}     the number of the line of the match
+//	{column}   the column of the match, according to --column-unit
+//	{offset}   the byte offset of the match in the file
+//	{text}     the line containing the match
+//	{match}    the text of the match
+//	{pattern}  the 1-based index of the pattern of the match, among the -e and -f patterns
+//	{1} {name} the text of a capture group of the pattern, by number or by name
+//
+// {{ and }} are a literal brace
+var templateFields = []string{"path", "line", "column", "offset", "text", "match", "pattern"}
+
+// a part of a --format template: literal text, or a field
+type templatePart struct {
+	text  string
+	field string //the name of the field, empty for literal text
+}
+
+// parse a --format template, a field is a name between braces
+func parseTemplate(format string) ([]templatePart, error) {
+	var parts []templatePart
+	var text strings.Builder
+	for x := 0; x < len(format); x++ {
+		switch {
+		case strings.HasPrefix(format[x:], "{{"), strings.HasPrefix(format[x:], "}}"):
+			text.WriteByte(format[x])
+			x++
+		case format[x] == '{':
+			end := strings.IndexByte(format[x:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("missing } in --format '%s'", format)
+			}
+			if text.Len() > 0 {
+				parts = append(parts, templatePart{text: text.String()})
+				text.Reset()
+			}
+			field := format[x+1 : x+end]
+			if field == "" {
+				return nil, fmt.Errorf("empty field in --format '%s'", format)
+			}
+			parts = append(parts, templatePart{field: field})
+			x += end
+		default:
+			text.WriteByte(format[x])
+		}
+	}
+	if text.Len() > 0 {
+		parts = append(parts, templatePart{text: text.String()})
+	}
+	return parts, nil
+}
+
+// check that every field of the template is known, a name that isn't a field has to be the name of a capture group
+func checkTemplate(parts []templatePart, patterns []*regex.Regexp) error {
+	for _, part := range parts {
+		if part.field == "" || slices.Contains(templateFields, part.field) {
+			continue
+		}
+		if _, err := strconv.Atoi(part.field); err == nil {
+			continue
+		}
+		found := false
+		for _, re := range patterns {
+			found = found || re.SubexpIndex(part.field) >= 0
+		}
+		if !found {
+			return fmt.Errorf("unknown field {%s} in --format, it is neither a field nor the name of a capture group", part.field)
+		}
+	}
+	return nil
+}
+
+// print the template for each match of a selected line
+func (p *printer) printTemplate(ol outputLine) {
+	for _, match := range ol.matches {
+		line, line_number, start := matchLine(ol, match)
+		x := matchingPattern(p.patterns, match)
+		for _, part := range p.opts.template {
+			switch part.field {
+			case "":
+				p.out.WriteString(part.text)
+			case "path":
+				p.out.WriteString(ol.filename)
+			case "line":
+				p.out.WriteString(strconv.Itoa(line_number))
+			case "column":
+				p.out.WriteString(strconv.Itoa(p.columnAt(line, match[0]-start)))
+			case "offset":
+				p.out.WriteString(strconv.Itoa(ol.offset + match[0]))
+			case "text":
+				p.out.Write(line)
+			case "match":
+				p.out.Write(ol.text[match[0]:match[1]])
+			case "pattern":
+				p.out.WriteString(strconv.Itoa(x + 1))
+			default:
+				p.out.Write(groupText(p.patterns, x, part.field, ol.text, match))
+			}
+		}
+		p.out.WriteByte(p.opts.terminator())
+	}
+}
+
+// the text of a capture group of a match, by number or by name. Empty when the pattern doesn't have
+// the group or when the group didn't take part in the match
+func groupText(patterns []*regex.Regexp, pattern int, group string, text []byte, match []int) []byte {
+	if pattern < 0 {
+		return nil
+	}
+	index, err := strconv.Atoi(group)
+	if err != nil {
+		index = patterns[pattern].SubexpIndex(group)
+	}
+	if index < 0 || 2*index+1 >= len(match) || match[2*index] < 0 {
+		return nil
+	}
+	return text[match[2*index]:match[2*index+1]]
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/codecrafters-io/grep-starter-go/internal/gitignore"
+)
+
+// the ignore files of a directory, from the lowest precedence to the highest
+var ignoreFiles = []string{filepath.Join(".git", "info", "exclude"), ".gitignore", ".ignore"}
+
+// call visit with every file to search, in order. With -r the directories are walked, skipping the hidden files
+// unless --hidden, and the .git directory along with the files matched by the ignore files unless --no-ignore.
+// The operands themselves are always searched, unless excluded by --include or --exclude. An error reading a directory is given to visit along with
+// the directory. Walking stops when visit returns false
+func walkFiles(opts *options, visit func(filename string, err error) bool) {
+	for _, filename := range opts.files {
+		if filename == "-" {
+			if !visit(filename, nil) {
+				return
+			}
+			continue
+		}
+		info, err := os.Stat(filename)
+		if !opts.recursive || err != nil || !info.IsDir() {
+			if opts.isSearched(filename) && !visit(filename, nil) {
+				return
+			}
+			continue
+		}
+		w := &walker{opts: opts, visit: visit}
+		if !w.walkDir(filename, nil) {
+			return
+		}
+	}
+}
+
+// walk the directories of an operand of -r
+type walker struct {
+	opts  *options
+	visit func(filename string, err error) bool
+}
+
+// walk a directory, matchers are the ignore files of its parent directories, the deepest one last.
+// Returns false once visit asked to stop
+func (w *walker) walkDir(dir string, matchers []*gitignore.Matcher) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return w.visit(dir, err)
+	}
+	if !w.opts.no_ignore {
+		matchers = appendIgnoreFiles(matchers, dir)
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") && !w.opts.hidden {
+			continue
+		} else if name == ".git" && entry.IsDir() && !w.opts.no_ignore { //the repository itself is never worth searching
+			continue
+		}
+		filename := filepath.Join(dir, name)
+		//like grep, the symbolic links found while walking are not followed, and the special files are not read
+		if entry.IsDir() {
+			if !isIgnored(matchers, filename, true) && !w.walkDir(filename, matchers) {
+				return false
+			}
+		} else if entry.Type().IsRegular() && w.opts.isSearched(filename) && !isIgnored(matchers, filename, false) {
+			if !w.visit(filename, nil) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// add the matchers of the ignore files of a directory
+func appendIgnoreFiles(matchers []*gitignore.Matcher, dir string) []*gitignore.Matcher {
+	//a new slice, the matchers of the parent directory are still used by its other subdirectories
+	matchers = matchers[:len(matchers):len(matchers)]
+	for _, name := range ignoreFiles {
+		m, err := gitignore.NewFromFile(filepath.Join(dir, name), dir)
+		if err != nil {
+			//a missing ignore file is the common case, an unreadable one is skipped as git does
+			continue
+		}
+		matchers = append(matchers, m)
+	}
+	return matchers
+}
+
+// check if a path is ignored, the ignore file of the deepest directory matching the path decides
+func isIgnored(matchers []*gitignore.Matcher, filename string, is_dir bool) bool {
+	for x := len(matchers) - 1; x >= 0; x-- {
+		if ignored, matched := matchers[x].Match(filename, is_dir); matched {
+			return ignored
+		}
+	}
+	return false
+}
+
+// check if a file is searched according to --include and --exclude. With --search-zip an archive is only
+// subject to --exclude, --include then applies to its members
+func (opts *options) isSearched(filename string) bool {
+	if opts.search_zip && isArchive(filename) {
+		return !opts.isExcluded(filename)
+	}
+	return opts.isSelectedFile(filename)
+}
@@ -0,0 +1,140 @@
+// Package gitignore matches paths against the patterns of .gitignore files, which are also the patterns
+// of .ignore files and of .git/info/exclude.
+//
+// A pattern without a slash, other than a trailing one, matches a name at any depth below the directory
+// of the file. Any other pattern is relative to that directory. A trailing slash only matches directories,
+// a leading ! re-includes what a previous pattern excluded, and the last pattern matching a path decides.
+// *, ? and [...] don't match a slash while ** matches any number of directories: **/name, dir/** and a/**/b.
+package gitignore
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// a pattern of an ignore file
+type rule struct {
+	segments []string //the pattern split on its slashes
+	negate   bool     //the pattern starts with !, matching paths are not ignored
+	dir_only bool     //the pattern ends with a slash, it only matches directories
+	anchored bool     //the pattern is relative to the directory of the file, otherwise it matches a name at any depth
+}
+
+// Matcher holds the patterns of an ignore file, they apply to the paths below the base directory of the file
+type Matcher struct {
+	base  string
+	rules []rule
+}
+
+// New returns a Matcher for the patterns read from r, as found in an ignore file of the base directory
+func New(base string, r io.Reader) (*Matcher, error) {
+	m := &Matcher{base: base}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if rl, ok := parseRule(scanner.Text()); ok {
+			m.rules = append(m.rules, rl)
+		}
+	}
+	return m, scanner.Err()
+}
+
+// NewFromFile returns a Matcher for the patterns of an ignore file, they apply below the base directory.
+// The error of a missing file can be checked with errors.Is(err, fs.ErrNotExist)
+func NewFromFile(filename, base string) (*Matcher, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return New(base, f)
+}
+
+// parse a line of an ignore file, ok is false for blank lines and comments
+func parseRule(line string) (rule, bool) {
+	line = trimTrailingSpaces(line)
+	if line == "" || line[0] == '#' {
+		return rule{}, false
+	}
+	var rl rule
+	if line[0] == '!' {
+		rl.negate = true
+		line = line[1:]
+	} else if line[0] == '\\' && len(line) > 1 && (line[1] == '!' || line[1] == '#') {
+		//\! and \# are a literal ! and #
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rl.dir_only = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return rule{}, false
+	}
+	rl.anchored = strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	for _, segment := range strings.Split(line, "/") {
+		//git uses [!...] for a negated class where path.Match uses [^...]
+		rl.segments = append(rl.segments, strings.ReplaceAll(segment, "[!", "[^"))
+	}
+	return rl, true
+}
+
+// trailing spaces are ignored unless they are escaped with a backslash
+func trimTrailingSpaces(line string) string {
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+		line = line[:len(line)-1]
+	}
+	return line
+}
+
+// Match reports whether the path, below the base directory, is ignored by the patterns.
+// matched is false when no pattern matches the path, to let the ignore files of parent directories decide.
+// Only the path itself is matched: the caller is expected to skip the content of an ignored directory
+func (m *Matcher) Match(filename string, is_dir bool) (ignored, matched bool) {
+	rel, err := filepath.Rel(m.base, filename)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false, false
+	}
+	segments := strings.Split(filepath.ToSlash(rel), "/")
+	//the last pattern matching the path decides
+	for x := len(m.rules) - 1; x >= 0; x-- {
+		rl := m.rules[x]
+		if rl.dir_only && !is_dir {
+			continue
+		}
+		if rl.anchored && matchSegments(rl.segments, segments) || !rl.anchored && matchSegments(rl.segments, segments[len(segments)-1:]) {
+			return !rl.negate, true
+		}
+	}
+	return false, false
+}
+
+// match the segments of a path against the segments of a pattern, ** matches any number of segments
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		//a trailing ** matches everything inside the directory, but not the directory itself
+		if len(pattern) == 1 {
+			return len(segments) > 0
+		}
+		for x := 0; x <= len(segments); x++ {
+			if matchSegments(pattern[1:], segments[x:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	if ok, err := path.Match(pattern[0], segments[0]); err != nil || !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], segments[1:])
+}
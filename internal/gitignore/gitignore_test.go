@@ -0,0 +1,68 @@
+package gitignore
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	patterns := `# a comment
+*.log
+!keep.log
+build/
+/root.txt
+docs/*.md
+**/generated/*.go
+vendor/**
+a/**/z
+\#hash
+trailing\ 
+[!a]x
+`
+	m, err := New("/repo", strings.NewReader(patterns))
+	if err != nil {
+		t.Fatalf("error returned: %s", err)
+	}
+	tests := []struct {
+		path    string
+		is_dir  bool
+		ignored bool
+		matched bool
+	}{
+		{"debug.log", false, true, true},
+		{"sub/dir/debug.log", false, true, true},
+		{"keep.log", false, false, true},
+		{"sub/keep.log", false, false, true},
+		{"build", true, true, true},
+		{"sub/build", true, true, true},
+		{"build", false, false, false},
+		{"root.txt", false, true, true},
+		{"sub/root.txt", false, false, false},
+		{"docs/readme.md", false, true, true},
+		{"docs/sub/readme.md", false, false, false},
+		{"generated/a.go", false, true, true},
+		{"x/y/generated/a.go", false, true, true},
+		{"vendor", true, false, false},
+		{"vendor/lib/a.go", false, true, true},
+		{"a/z", false, true, true},
+		{"a/b/c/z", false, true, true},
+		{"#hash", false, true, true},
+		{"trailing ", false, true, true},
+		{"bx", false, true, true},
+		{"ax", false, false, false},
+		{"main.go", false, false, false},
+	}
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			ignored, matched := m.Match(filepath.Join("/repo", test.path), test.is_dir)
+			if ignored != test.ignored || matched != test.matched {
+				t.Fatalf("got ignored %v matched %v expected: %v %v", ignored, matched, test.ignored, test.matched)
+			}
+		})
+	}
+	//paths outside of the base directory are not matched
+	if _, matched := m.Match("/other/debug.log", false); matched {
+		t.Fatal("expected no match outside of the base directory")
+	}
+}
@@ -0,0 +1,25 @@
+// Package mmap maps the content of files in memory, read only, on the systems that support it.
+// Elsewhere Map fails with ErrUnsupported and the file has to be read instead
+package mmap
+
+import (
+	"errors"
+	"os"
+)
+
+// ErrUnsupported is returned on the systems without memory mapped files
+var ErrUnsupported = errors.ErrUnsupported
+
+// Map maps the first size bytes of a file in memory, read only. The mapping stays valid once the file is closed
+// and has to be released with Unmap. The content of the file must not be truncated while it is mapped
+func Map(f *os.File, size int) ([]byte, error) {
+	if size <= 0 {
+		return nil, errors.New("mmap: invalid size")
+	}
+	return mmap(f, size)
+}
+
+// Unmap releases a mapping returned by Map
+func Unmap(data []byte) error {
+	return munmap(data)
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package mmap
+
+import "os"
+
+func mmap(f *os.File, size int) ([]byte, error) {
+	return nil, ErrUnsupported
+}
+
+func munmap(data []byte) error {
+	return ErrUnsupported
+}
@@ -0,0 +1,30 @@
+package mmap
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMap(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "file")
+	os.WriteFile(filename, []byte("mapped content\n"), 0o644)
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatalf("error returned: %s", err)
+	}
+	data, err := Map(f, 15)
+	f.Close()
+	if errors.Is(err, ErrUnsupported) {
+		t.Skip("memory mapped files are not supported")
+	} else if err != nil {
+		t.Fatalf("error returned: %s", err)
+	}
+	if string(data) != "mapped content\n" {
+		t.Fatalf("got %q", data)
+	}
+	if err := Unmap(data); err != nil {
+		t.Fatalf("error returned: %s", err)
+	}
+}
@@ -0,0 +1,10 @@
+// Package term tells if a file is a terminal, without cgo. The terminal is asked for its settings the way isatty does,
+// a system without such a check has no terminal
+package term
+
+import "os"
+
+// IsTerminal checks if the file is a terminal
+func IsTerminal(f *os.File) bool {
+	return isTerminal(f)
+}
@@ -0,0 +1,16 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+
+package term
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// the same check as isatty: only a terminal answers the TIOCGETA ioctl
+func isTerminal(f *os.File) bool {
+	var termios syscall.Termios
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), syscall.TIOCGETA, uintptr(unsafe.Pointer(&termios)))
+	return errno == 0
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd && !windows
+
+package term
+
+import "os"
+
+// there is no terminal check on the other systems, the output is never colored by default
+func isTerminal(f *os.File) bool {
+	return false
+}
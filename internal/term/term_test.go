@@ -0,0 +1,24 @@
+package term
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsTerminal(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "file"))
+	if err != nil {
+		t.Fatalf("error returned: %s", err)
+	}
+	defer f.Close()
+	if IsTerminal(f) {
+		t.Fatalf("a regular file is reported as a terminal")
+	}
+	if null, err := os.Open(os.DevNull); err == nil {
+		defer null.Close()
+		if IsTerminal(null) {
+			t.Fatalf("%s is reported as a terminal", os.DevNull)
+		}
+	}
+}
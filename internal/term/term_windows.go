@@ -0,0 +1,14 @@
+//go:build windows
+
+package term
+
+import (
+	"os"
+	"syscall"
+)
+
+// only a console has a console mode, NUL is a character device too
+func isTerminal(f *os.File) bool {
+	var mode uint32
+	return syscall.GetConsoleMode(syscall.Handle(f.Fd()), &mode) == nil
+}
@@ -0,0 +1,126 @@
+package regex
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Node is a node of the syntax tree of a pattern, as the matcher splits it into subpatterns.
+// The root is the whole pattern, its children are the flags, the anchors and the subpatterns in the order
+// they are matched, and a capture group has the subpatterns of its content as children
+type Node struct {
+	Kind       string  `json:"kind"`                 //pattern, flags, anchor, literal, digit, word, class, negated_class, group, alternation, backreference
+	Text       string  `json:"text"`                 //the source of the node, quantifier included
+	Start      int     `json:"start"`                //byte offset of the node in the pattern
+	End        int     `json:"end"`                  //byte offset following the node
+	Quantifier string  `json:"quantifier,omitempty"` //+ or ?, empty when the node is matched once
+	Group      int     `json:"group,omitempty"`      //the number of a capture group, or the group a backreference refers to
+	Name       string  `json:"name,omitempty"`       //the name of a named capture group
+	Children   []*Node `json:"children,omitempty"`
+}
+
+// Explain checks the syntax of the pattern and returns its syntax tree.
+// An invalid pattern is reported with a *SyntaxError
+func Explain(pattern string) (*Node, error) {
+	group_names, err := checkSyntax(pattern)
+	if err != nil {
+		return nil, err
+	}
+	root := &Node{Kind: "pattern", Text: pattern, End: len(pattern)}
+	_, length := parseFlags(pattern)
+	if length > 0 {
+		root.Children = append(root.Children, &Node{Kind: "flags", Text: pattern[:length], End: length})
+	}
+	e := explainer{pattern: pattern, group_names: group_names}
+	root.Children = append(root.Children, e.explain(compile(pattern), length)...)
+	return root, nil
+}
+
+// the state of the walk of a compiled program along its source
+type explainer struct {
+	pattern     string
+	group_names []string
+	groups      int //the number of capture groups met so far
+}
+
+// the nodes of the subpatterns of a program, whose source starts at offset start of the pattern
+func (e *explainer) explain(prog *program, start int) []*Node {
+	var nodes []*Node
+	cursor := start
+	if prog.match_start && strings.HasPrefix(prog.pattern, "^") {
+		nodes = append(nodes, e.node("anchor", cursor, cursor+1))
+		cursor++
+	}
+	for x, pat := range prog.patterns {
+		length := len(pat.pattern)
+		end := cursor + length
+		if pat.sign == plusSign || pat.sign == optionalSign {
+			end++
+		}
+		node := e.node(subpatternKind(pat.pattern), cursor, end)
+		if pat.sign == plusSign || pat.sign == optionalSign {
+			node.Quantifier = string(pat.sign)
+		}
+		switch node.Kind {
+		case "backreference":
+			node.Group, _ = strconv.Atoi(e.pattern[cursor+1 : cursor+length])
+		case "group":
+			e.groups++
+			node.Group = e.groups
+			if node.Group <= len(e.group_names) {
+				node.Name = e.group_names[node.Group-1]
+			}
+			content := cursor + 1
+			if _, name_length, ok := groupName(e.pattern[content:]); ok {
+				content += name_length
+			}
+			if prog.alternatives[x] != nil {
+				node.Children = []*Node{e.alternation(prog.alternatives[x], content)}
+			} else if prog.groups[x] != nil {
+				node.Children = e.explain(prog.groups[x], content)
+			}
+		}
+		nodes = append(nodes, node)
+		cursor = end
+	}
+	if prog.match_end && strings.HasSuffix(prog.pattern, "$") {
+		nodes = append(nodes, e.node("anchor", cursor, cursor+1))
+	}
+	return nodes
+}
+
+// the node of the alternatives of a group, each one a literal, starting at offset start of the pattern
+func (e *explainer) alternation(alternatives []string, start int) *Node {
+	node := e.node("alternation", start, start+len(strings.Join(alternatives, "|")))
+	cursor := start
+	for _, alternative := range alternatives {
+		node.Children = append(node.Children, e.node("literal", cursor, cursor+len(alternative)))
+		//the groups of an alternative are matched as literals, they still take a number
+		e.groups += strings.Count(alternative, "(")
+		cursor += len(alternative) + 1
+	}
+	return node
+}
+
+func (e *explainer) node(kind string, start, end int) *Node {
+	return &Node{Kind: kind, Text: e.pattern[start:end], Start: start, End: end}
+}
+
+// the kind of node of a subpattern
+func subpatternKind(pattern string) string {
+	switch {
+	case isBackReference(pattern):
+		return "backreference"
+	case isDigitMatch(pattern):
+		return "digit"
+	case isAlphaNumericMatch(pattern):
+		return "word"
+	case isCaptureGroupMatch(pattern):
+		return "group"
+	case isCharacterGroupMatch(pattern) && strings.HasPrefix(pattern, "[^"):
+		return "negated_class"
+	case isCharacterGroupMatch(pattern):
+		return "class"
+	}
+	return "literal"
+}
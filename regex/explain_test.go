@@ -0,0 +1,77 @@
+package regex
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testExplain = []struct {
+	description string
+	pattern     string
+	expected    []string
+}{
+	{
+		description: "literals and quantifiers",
+		pattern:     "bba+\\d?",
+		expected:    []string{"pattern bba+\\d? 0-7", " literal bb 0-2", " literal a+ 2-4 +", " digit \\d? 4-7 ?"},
+	},
+	{
+		description: "anchors and flags",
+		pattern:     "(?m)^[^ab]\\w$",
+		expected:    []string{"pattern (?m)^[^ab]\\w$ 0-13", " flags (?m) 0-4", " anchor ^ 4-5", " negated_class [^ab] 5-10", " word \\w 10-12", " anchor $ 12-13"},
+	},
+	{
+		description: "named group and alternation",
+		pattern:     "(?<animal>cat|dog)s",
+		expected: []string{
+			"pattern (?<animal>cat|dog)s 0-19", " group (?<animal>cat|dog) 0-18 #1 animal", "  alternation cat|dog 10-17",
+			"   literal cat 10-13", "   literal dog 14-17", " literal s 18-19",
+		},
+	},
+	{
+		description: "nested groups and backreference",
+		pattern:     "(a(b)[xy])\\2",
+		expected: []string{
+			"pattern (a(b)[xy])\\2 0-12", " group (a(b)[xy]) 0-10 #1", "  literal a 1-2", "  group (b) 2-5 #2",
+			"   literal b 3-4", "  class [xy] 5-9", " backreference \\2 10-12 #2",
+		},
+	},
+}
+
+// flatten a tree into a line per node, indented by its depth
+func flattenNode(node *Node, depth int) []string {
+	line := fmt.Sprintf("%s%s %s %d-%d", strings.Repeat(" ", depth), node.Kind, node.Text, node.Start, node.End)
+	if node.Quantifier != "" {
+		line += " " + node.Quantifier
+	}
+	if node.Group > 0 {
+		line += fmt.Sprintf(" #%d", node.Group)
+	}
+	if node.Name != "" {
+		line += " " + node.Name
+	}
+	lines := []string{line}
+	for _, child := range node.Children {
+		lines = append(lines, flattenNode(child, depth+1)...)
+	}
+	return lines
+}
+
+func TestExplain(t *testing.T) {
+	for _, tp := range testExplain {
+		t.Run(tp.description, func(t *testing.T) {
+			root, err := Explain(tp.pattern)
+			if err != nil {
+				t.Fatalf("error returned: %s", err)
+			}
+			if actual := flattenNode(root, 0); !reflect.DeepEqual(actual, tp.expected) {
+				t.Fatalf("got %q expected: %q", actual, tp.expected)
+			}
+		})
+	}
+	if _, err := Explain("a(b"); err == nil {
+		t.Fatalf("no error returned for an invalid pattern")
+	}
+}
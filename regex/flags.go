@@ -0,0 +1,51 @@
+package regex
+
+// the flags of a pattern, written at its very start: (?s), (?m) or both as (?sm)
+type flags struct {
+	dot_newline bool //s: the wildcard matches a newline
+	multi_line  bool //m: ^ and $ also match right after and right before a newline
+}
+
+// the flags at the start of the pattern along with the length of their syntax, 0 if the pattern doesn't start with flags
+func parseFlags(pattern string) (flags, int) {
+	var f flags
+	if len(pattern) < 4 || pattern[0] != '(' || pattern[1] != '?' {
+		return f, 0
+	}
+	for x := 2; x < len(pattern); x++ {
+		switch pattern[x] {
+		case 's':
+			f.dot_newline = true
+		case 'm':
+			f.multi_line = true
+		case ')':
+			if x == 2 {
+				return flags{}, 0
+			}
+			return f, x + 1
+		default:
+			return flags{}, 0
+		}
+	}
+	return flags{}, 0
+}
+
+// set the flags of a program and of its capture groups
+func (prog *program) setFlags(f flags) {
+	prog.flags = f
+	for _, group := range prog.groups {
+		if group != nil {
+			group.setFlags(f)
+		}
+	}
+}
+
+// check if the byte at offset x of the line is where a ^ anchor can match
+func (gh *grepHandler) atLineStart(x int) bool {
+	return x == 0 || gh.multi_line && gh.line[x-1] == '\n'
+}
+
+// check if the byte at offset x of the line is where a $ anchor can match
+func (gh *grepHandler) atLineEnd(x int) bool {
+	return x == len(gh.line) || gh.multi_line && gh.line[x] == '\n'
+}
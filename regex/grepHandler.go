@@ -0,0 +1,483 @@
+package regex
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// the quantifier of a subpattern, or the alternation of a capture group
+type sign string
+
+const (
+	plusSign        sign = "+"
+	alternationSign sign = "|"
+	optionalSign    sign = "?"
+)
+
+// a part of the pattern matched on its own: a literal, a character class, a capture group or a backreference
+type subpattern struct {
+	pattern  string
+	sign     sign
+	nextbyte byte //for a quantified negated character group, the byte of the next subpattern that ends it
+}
+
+// a subpattern along with the part of the line it matched
+type matchedPattern struct {
+	start, end int //byte offsets of the match in the line, the text of the match is line[start:end]
+	subpattern
+}
+
+// the state of a match of a compiled program against a line, the program itself is never modified
+type grepHandler struct {
+	*program                                                         //the compiled pattern
+	line                                            []byte           //the line to match
+	line_cursor, pattern_cursor, line_cursor_offset int              //cursors
+	matched_patterns                                []matchedPattern //keep track of the pattern we matched
+	backreferences                                  [][]int          //keep track of the [start, end] byte offsets of the capture groups, in the order of the groups
+}
+
+func newProgramHandler(line []byte, prog *program) *grepHandler {
+	return &grepHandler{program: prog, line: line, backreferences: make([][]int, 0), matched_patterns: make([]matchedPattern, 0)}
+}
+
+func newMatchedPattern(start, end int, pattern subpattern) matchedPattern {
+	return matchedPattern{start: start, end: end, subpattern: pattern}
+}
+
+// split the raw pattern into subpatterns, the pattern is expected to be valid
+func (prog *program) splitPatterns() {
+	prog.patterns = make([]subpattern, 0)
+	var current_pattern string
+	backslash := false       // \w \d
+	character_group := false //[sbd] or [^dhb]
+	capture_group := false
+	var parenthesis_count int
+	alternation := false
+	backreference := false
+	var previousChar rune
+	var currentSign sign
+	for x, b := range prog.pattern {
+		//a backreference ends with its digits, it is a subpattern of its own unless a quantifier follows
+		if backreference && (b < '0' || b > '9') {
+			backreference = false
+			if b != '+' && b != '?' {
+				prog.patterns = append(prog.patterns, subpattern{pattern: current_pattern})
+				current_pattern = ""
+			}
+		}
+		switch b {
+		case '|':
+			alternation = true
+			current_pattern += string(b)
+		case '(':
+			capture_group = true
+			parenthesis_count++
+			if current_pattern != "" && parenthesis_count <= 1 {
+				prog.patterns = append(prog.patterns, subpattern{pattern: current_pattern})
+				current_pattern = ""
+			}
+			current_pattern += string(b)
+		case ')':
+			current_pattern += string(b)
+			parenthesis_count--
+			if parenthesis_count == 0 {
+				capture_group = false
+			}
+			if currentSign != "" {
+				p := subpattern{pattern: current_pattern, sign: currentSign}
+				prog.patterns = append(prog.patterns, p)
+				currentSign = ""
+				current_pattern = ""
+			} else if alternation && parenthesis_count < 1 {
+				alternation = false
+				p := subpattern{pattern: current_pattern, sign: alternationSign}
+				prog.patterns = append(prog.patterns, p)
+				current_pattern = ""
+			} else if parenthesis_count < 1 {
+				p := subpattern{pattern: current_pattern}
+				prog.patterns = append(prog.patterns, p)
+				current_pattern = ""
+			}
+		case '[':
+			character_group = true
+			if !capture_group && current_pattern != "" {
+				prog.patterns = append(prog.patterns, subpattern{pattern: current_pattern})
+				current_pattern = ""
+			}
+			current_pattern += string(b)
+		case ']':
+			if character_group && !capture_group {
+				character_group = false
+				current_pattern += string(b)
+				p := subpattern{pattern: current_pattern}
+				prog.patterns = append(prog.patterns, p)
+				current_pattern = ""
+			} else {
+				current_pattern += string(b)
+			}
+			character_group = false
+		case '+', '?':
+			if capture_group || character_group { //we add the quantifier as a literal inside the capture group && character group
+				current_pattern += string(b)
+			} else if previousChar == ']' && current_pattern == "" && len(prog.patterns) > 0 && isCharacterGroupMatch(prog.patterns[len(prog.patterns)-1].pattern) { // we add the quantifier as a sign to the character group subpattern
+				prog.patterns[len(prog.patterns)-1].sign = sign(b)
+			} else if current_pattern == "" && len(prog.patterns) > 0 && isAlphaOrDigitMatch(prog.patterns[len(prog.patterns)-1].pattern) { //we add the quantifier to a \w or \d previous pattern
+				prog.patterns[len(prog.patterns)-1].sign = sign(b)
+			} else if isBackReference(current_pattern) { // \1+
+				prog.patterns = append(prog.patterns, subpattern{pattern: current_pattern, sign: sign(b)})
+				current_pattern = ""
+			} else if len(current_pattern) > 1 { // bba+ -> bb , a +
+				last_char := current_pattern[len(current_pattern)-1]
+				current_pattern = current_pattern[:len(current_pattern)-1]                                   //trim the current pattern
+				prog.patterns = append(prog.patterns, subpattern{pattern: current_pattern})                  // add the bb
+				prog.patterns = append(prog.patterns, subpattern{pattern: string(last_char), sign: sign(b)}) // add the a+
+				current_pattern = ""
+			} else if len(current_pattern) == 1 {
+				prog.patterns = append(prog.patterns, subpattern{pattern: current_pattern, sign: sign(b)})
+				current_pattern = ""
+			} else {
+				current_pattern += string(b)
+			}
+		case '\\':
+			if backslash {
+				backslash = false
+			} else {
+				if current_pattern != "" && !character_group && !capture_group {
+					p := subpattern{pattern: current_pattern}
+					prog.patterns = append(prog.patterns, p)
+					current_pattern = ""
+				}
+				backslash = true
+			}
+			current_pattern += string(b)
+		case 'w', 'd':
+			if backslash {
+				backslash = false
+				current_pattern += string(b)
+				if !character_group && !capture_group {
+					p := subpattern{pattern: current_pattern}
+					prog.patterns = append(prog.patterns, p)
+					current_pattern = ""
+				}
+			} else {
+				current_pattern += string(b)
+			}
+		default:
+
+			if b == '^' && x == 0 { //start of string anchor
+				prog.match_start = true
+				continue
+			} else if b == '$' && x+1 == len(prog.pattern) { //end of string anchor
+				prog.match_end = true
+				continue
+			} else if b >= '1' && b <= '9' && backslash || backreference && b >= '0' && b <= '9' {
+				//in a group or a character group the backreference is part of a larger subpattern
+				backreference = !character_group && parenthesis_count < 1
+				backslash = false
+				current_pattern += string(b)
+			} else {
+				current_pattern += string(b)
+			}
+		}
+		previousChar = b
+	}
+	if current_pattern != "" {
+		p := subpattern{pattern: current_pattern}
+		prog.patterns = append(prog.patterns, p)
+	}
+}
+
+// match additional chars
+func (gh *grepHandler) matchQuantifierPlus(matcher func(b byte) bool) {
+	for gh.line_cursor < len(gh.line) && matcher(gh.line[gh.line_cursor]) {
+		gh.line_cursor++
+	}
+}
+
+// Wrapper for matching \w or \d, in combination with signs such as + ?
+func (gh *grepHandler) matchCharOrDigit() bool {
+	current_pattern := gh.patterns[gh.pattern_cursor]
+	matcher := isDigit
+	if isAlphaNumericMatch(current_pattern.pattern) {
+		matcher = isAlphaNumeric
+	}
+	if gh.line_cursor >= len(gh.line) || !matcher(gh.line[gh.line_cursor]) {
+		return current_pattern.sign == optionalSign
+	}
+	start := gh.line_cursor
+	gh.line_cursor++
+	if current_pattern.sign == plusSign {
+		gh.matchQuantifierPlus(matcher)
+	}
+	gh.matched_patterns = append(gh.matched_patterns, newMatchedPattern(start, gh.line_cursor, current_pattern))
+	return true
+}
+
+// look for the non-overlapping matches in the line, stopping after n matches when n >= 0.
+// deliver is called with the byte offsets of each match, while the handler still holds the capture groups of the match.
+// an empty match right after the previous match is not reported, like Go's regexp
+func (gh *grepHandler) forEachMatch(n int, deliver func(start, end int)) error {
+	prev_end := -1
+	for pos, count := 0, 0; pos <= len(gh.line) && (n < 0 || count < n); {
+		start, end, ok, err := gh.findMatch(pos)
+		if err != nil {
+			return err
+		} else if !ok {
+			break
+		}
+		if start < end {
+			deliver(start, end)
+			count++
+			pos = end
+		} else {
+			if start != prev_end {
+				deliver(start, end)
+				count++
+			}
+			pos = end + 1
+		}
+		prev_end = end
+	}
+	return nil
+}
+
+// look for the leftmost match starting at or after the from offset, returns the byte offsets of the match.
+// the literals required by the pattern are looked for first, to skip the offsets where no match can start
+func (gh *grepHandler) findMatch(from int) (int, int, bool, error) {
+	required := gh.prefilter.nextRequired(gh.line, from)
+	for gh.line_cursor_offset = from; required >= 0 && gh.line_cursor_offset <= len(gh.line); gh.line_cursor_offset++ {
+		gh.line_cursor_offset = gh.prefilter.nextStart(gh.line, gh.line_cursor_offset)
+		if gh.line_cursor_offset < 0 {
+			break
+		}
+		//past the required literal, the match needs the next one
+		if required < gh.line_cursor_offset {
+			if required = gh.prefilter.nextRequired(gh.line, gh.line_cursor_offset); required < 0 {
+				break
+			}
+		}
+		//the start of string anchor only allows a match at the beginning of the line, or of any line with (?m)
+		if gh.match_start && !gh.atLineStart(gh.line_cursor_offset) {
+			if !gh.multi_line {
+				break
+			}
+			continue
+		}
+		gh.resetSearch()
+		ok, err := gh.matchHere()
+		if err != nil {
+			return 0, 0, false, err
+		} else if ok {
+			return gh.line_cursor_offset, gh.line_cursor, true, nil
+		}
+		//like GNU grep, a match that isn't a whole word is retried at the next offset
+	}
+	return 0, 0, false, nil
+}
+
+// check if the match is a whole word, it must not be preceded or followed by a word character
+func (gh *grepHandler) isWordBounded(start, end int) bool {
+	if start == end {
+		return false
+	} else if start > 0 && isAlphaNumeric(gh.line[start-1]) {
+		return false
+	}
+	return end >= len(gh.line) || !isAlphaNumeric(gh.line[end])
+}
+
+// match every subpattern one after the other, starting at the line cursor
+func (gh *grepHandler) matchHere() (bool, error) {
+	return gh.matchFrom(0)
+}
+
+// match the subpatterns starting with the one at index first. An alternation tries its alternatives in turn
+// until the rest of the pattern matches, so the match can go on after an alternative that matched too early
+func (gh *grepHandler) matchFrom(first int) (bool, error) {
+	//go through each subpattern
+	for gh.pattern_cursor = first; gh.pattern_cursor < len(gh.patterns); gh.pattern_cursor++ {
+		//tracks if we can match the subpattern, if not, no match
+		part_ok := false
+		pattern := gh.patterns[gh.pattern_cursor].pattern
+		if isDigitMatch(pattern) || isAlphaNumericMatch(pattern) { //\w or \d
+			part_ok = gh.matchCharOrDigit()
+		} else if isBackReference(pattern) { // \1 \2 \3 etc....
+			index, err := strconv.Atoi(pattern[1:])
+			if err != nil {
+				return false, fmt.Errorf("invalid backreference %s: %w", pattern, err)
+			}
+			//a group that didn't take part in the match, or isn't over yet, can't be referenced
+			if index-1 >= len(gh.backreferences) || gh.backreferences[index-1][0] < 0 {
+				return false, nil
+			}
+			group := gh.backreferences[index-1]
+			current_pattern := string(gh.line[group[0]:group[1]])
+			start, sign := gh.line_cursor, gh.patterns[gh.pattern_cursor].sign
+			for strings.HasPrefix(string(gh.line[gh.line_cursor:]), current_pattern) {
+				gh.line_cursor += len(current_pattern)
+				//an empty group matches once, however many times it is repeated
+				if sign != plusSign || current_pattern == "" {
+					break
+				}
+			}
+			if gh.line_cursor > start || current_pattern == "" && strings.HasPrefix(string(gh.line[start:]), current_pattern) {
+				gh.matched_patterns = append(gh.matched_patterns, newMatchedPattern(start, gh.line_cursor, gh.patterns[gh.pattern_cursor]))
+				part_ok = true
+			} else {
+				part_ok = sign == optionalSign
+			}
+		} else if isCharacterGroupMatch(pattern) { // [anything with square brackets]
+			var isPositive bool
+			if gh.patterns[gh.pattern_cursor].pattern[1] != '^' {
+				isPositive = true
+			}
+			part_ok = gh.matchCharacterGroupPattern(pattern, isPositive)
+		} else if isCaptureGroupMatch(pattern) { // (anything with parenthesis)
+			//the group was compiled along with the pattern, either as alternatives or as its own program
+			if alternatives := gh.alternatives[gh.pattern_cursor]; alternatives != nil { //if it is an alternation in the capture group
+				//the alternation matches the rest of the pattern too
+				return gh.matchCaptureGroupAlternation(alternatives)
+			} else if group := gh.groups[gh.pattern_cursor]; group != nil { //not an alternation in the capture group
+				var err error
+				if part_ok, err = gh.matchCaptureGroupSubPatterns(group); err != nil {
+					return false, err
+				}
+			} else { //empty group, it matches the empty string
+				gh.backreferences = append(gh.backreferences, []int{gh.line_cursor, gh.line_cursor})
+				part_ok = true
+			}
+		} else { //anything that doesn't fall in previous scenarios
+			if len(gh.patterns[gh.pattern_cursor].pattern) == 1 {
+				part_ok = gh.matchCharacter()
+			} else {
+				part_ok = gh.matchString()
+			}
+		}
+		if !part_ok {
+			return false, nil
+		}
+	}
+	if gh.match_end && !gh.atLineEnd(gh.line_cursor) {
+		return false, nil
+	}
+	//like GNU grep, a match that isn't a whole word is retried with the next alternative, then at the next offset
+	return !gh.match_word || gh.isWordBounded(gh.line_cursor_offset, gh.line_cursor), nil
+}
+
+// match a single character, can match more than once wih quantifiers
+func (gh *grepHandler) matchCharacter() bool {
+	current_pattern := gh.patterns[gh.pattern_cursor]
+	start := gh.line_cursor
+	for gh.line_cursor < len(gh.line) && matchesByte(current_pattern.pattern[0], gh.line[gh.line_cursor], gh.dot_newline) {
+		gh.line_cursor++
+		//match only once
+		if current_pattern.sign != plusSign {
+			break
+		}
+	}
+	if gh.line_cursor == start {
+		return current_pattern.sign == optionalSign
+	}
+	gh.matched_patterns = append(gh.matched_patterns, newMatchedPattern(start, gh.line_cursor, current_pattern))
+	return true
+}
+
+// reset the pattern cursor and the state of the previous attempt, the search starts over at the line cursor offset
+func (gh *grepHandler) resetSearch() {
+	gh.backreferences = gh.backreferences[:0]
+	gh.matched_patterns = gh.matched_patterns[:0]
+	gh.pattern_cursor = 0
+	gh.line_cursor = gh.line_cursor_offset
+}
+
+// match the current pattern (its not one of the predefined ones, it's a string) with the line
+func (gh *grepHandler) matchString() bool {
+	current_pattern := gh.patterns[gh.pattern_cursor]
+	//a wildcard in the pattern matches any byte
+	if !hasPrefixWithWildcard(gh.line[gh.line_cursor:], current_pattern.pattern, gh.dot_newline) {
+		return false
+	}
+	//it's a match so we increment the line cursor by the length of the pattern
+	gh.matched_patterns = append(gh.matched_patterns, newMatchedPattern(gh.line_cursor, gh.line_cursor+len(current_pattern.pattern), current_pattern))
+	gh.line_cursor += len(current_pattern.pattern)
+	return true
+}
+
+// (\w+ \d+) -> "\w+", " ", "\d+"
+// (\w\d) -> "\w", "\d"
+func (gh *grepHandler) matchCaptureGroupSubPatterns(group *program) (bool, error) {
+	subgh := newProgramHandler(gh.line, group)
+	//the group has to match right at the line cursor
+	subgh.line_cursor_offset = gh.line_cursor
+	subgh.resetSearch()
+	//the backreferences of the group can refer to the groups before it. The group itself comes next,
+	//it can't be referred to before it is over
+	own := len(gh.backreferences)
+	subgh.backreferences = append(subgh.backreferences, gh.backreferences...)
+	subgh.backreferences = append(subgh.backreferences, []int{-1, -1})
+	ok, err := subgh.matchHere()
+	if err != nil {
+		return false, err
+	} else if ok {
+		start := gh.line_cursor
+		gh.line_cursor = subgh.line_cursor
+		gh.backreferences = append(gh.backreferences, []int{start, gh.line_cursor})
+		//then the nested groups
+		gh.backreferences = append(gh.backreferences, subgh.backreferences[own+1:]...)
+		gh.matched_patterns = append(gh.matched_patterns, newMatchedPattern(start, gh.line_cursor, gh.patterns[gh.pattern_cursor]))
+		return true, nil
+	}
+	return false, nil
+}
+
+// (cat|dog) -> "cat", "dog". Each alternative matching at the line cursor is followed by the rest of the pattern,
+// the state of the match is rolled back before trying the next one
+func (gh *grepHandler) matchCaptureGroupAlternation(alternatives []string) (bool, error) {
+	x, start := gh.pattern_cursor, gh.line_cursor
+	backreferences, matched_patterns := len(gh.backreferences), len(gh.matched_patterns)
+	for _, pat := range alternatives {
+		if !hasPrefixWithWildcard(gh.line[start:], pat, gh.dot_newline) {
+			continue
+		}
+		gh.line_cursor = start + len(pat)
+		gh.backreferences = append(gh.backreferences[:backreferences], []int{start, gh.line_cursor})
+		gh.matched_patterns = append(gh.matched_patterns[:matched_patterns], newMatchedPattern(start, gh.line_cursor, gh.patterns[x]))
+		if ok, err := gh.matchFrom(x + 1); err != nil || ok {
+			return ok, err
+		}
+	}
+	gh.line_cursor = start
+	gh.backreferences, gh.matched_patterns = gh.backreferences[:backreferences], gh.matched_patterns[:matched_patterns]
+	return false, nil
+}
+
+func (gh *grepHandler) matchCharacterGroupPattern(pattern string, isPositiveMatch bool) bool {
+	charGroupPattern := getCharacterGroupPattern(pattern, isPositiveMatch)
+	return gh.matchCharacterGroup(gh.line[gh.line_cursor:], charGroupPattern, isPositiveMatch, gh.patterns[gh.pattern_cursor].sign)
+}
+
+func (gh *grepHandler) matchCharacterGroup(line, characterGroup []byte, isPositive bool, sign sign) bool {
+	current_pattern := gh.patterns[gh.pattern_cursor]
+	var length int
+
+	switch sign {
+	case plusSign: //match as many byte as we can
+		for ; length < len(line) && contains(characterGroup, line[length]) == isPositive; length++ {
+			//grab the next pattern first byte, stop matching when it is found otherwise we run the risk to
+			//greedy match until the line is over
+			if !isPositive && current_pattern.nextbyte != 0 && line[length] == current_pattern.nextbyte {
+				break
+			}
+		}
+	default: //match the first byte of the line
+		if len(line) > 0 && contains(characterGroup, line[0]) == isPositive {
+			length = 1
+		}
+	}
+	if length == 0 {
+		return sign == optionalSign
+	}
+	gh.matched_patterns = append(gh.matched_patterns, newMatchedPattern(gh.line_cursor, gh.line_cursor+length, current_pattern))
+	gh.line_cursor += length
+	return true
+}
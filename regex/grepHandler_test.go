@@ -0,0 +1,616 @@
+package regex
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// a handler for a raw pattern, the tests compile it with extractPatterns
+func newGrepHandler(line []byte, pattern string) *grepHandler {
+	return newProgramHandler(line, &program{pattern: pattern})
+}
+
+// compile the raw pattern into subpatterns, returns a *SyntaxError if the pattern is invalid
+func (gh *grepHandler) extractPatterns() error {
+	if _, err := checkSyntax(gh.pattern); err != nil {
+		return err
+	}
+	gh.program = compile(gh.pattern)
+	return nil
+}
+
+// look for the leftmost match in the line, returns its [start, end] byte offsets or nil if there is no match
+func (gh *grepHandler) matchPatterns() ([]int, error) {
+	start, end, ok, err := gh.findMatch(0)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return []int{start, end}, nil
+}
+
+// look for every non-overlapping match in the line, each match is returned as its [start, end] byte offsets
+func (gh *grepHandler) findAllMatches() ([][]int, error) {
+	matches := make([][]int, 0)
+	err := gh.forEachMatch(-1, func(start, end int) {
+		matches = append(matches, []int{start, end})
+	})
+	return matches, err
+}
+
+var testSimplePatternExtraction = []struct {
+	description string
+	pattern     string
+	expected    []string
+}{
+	{
+		description: "single character",
+		pattern:     "d",
+		expected:    []string{"d"},
+	},
+	{
+		description: "single character",
+		pattern:     "w",
+		expected:    []string{"w"},
+	},
+	{
+		description: "single character",
+		pattern:     "q",
+		expected:    []string{"q"},
+	},
+	{
+		description: "multiple character classes",
+		pattern:     "\\d\\w",
+		expected:    []string{"\\d", "\\w"},
+	},
+	{
+		description: "character classe and single char",
+		pattern:     "\\dw",
+		expected:    []string{"\\d", "w"},
+	},
+	{
+		description: "two character classes and single char",
+		pattern:     "\\d\\ww",
+		expected:    []string{"\\d", "\\w", "w"},
+	},
+	{
+		description: "two character classes and single char",
+		pattern:     "\\d \\ww",
+		expected:    []string{"\\d", " ", "\\w", "w"},
+	},
+	{
+		description: "character group",
+		pattern:     "[abc]",
+		expected:    []string{"[abc]"},
+	},
+	{
+		description: "end of string anchor",
+		pattern:     "a$",
+		expected:    []string{"a"},
+	},
+}
+
+var testPatternWithQuantifierExtraction = []struct {
+	description string
+	pattern     string
+	expected    []subpattern
+}{
+	{
+		description: "VComplex capture group",
+		pattern:     "((c.t|d.g) and (f..h|b..d)), \\2 with \\3, \\1",
+		expected:    []subpattern{{pattern: "((c.t|d.g) and (f..h|b..d))", sign: alternationSign}, {pattern: ", "}, {pattern: "\\2"}, {pattern: " with "}, {pattern: "\\3"}, {pattern: ", "}, {pattern: "\\1"}},
+	},
+	{
+		description: "VComplex capture group",
+		pattern:     "(([abc]+)-([def]+)) is \\1, not ([^xyz]+), \\2, or \\3",
+		expected:    []subpattern{{pattern: "(([abc]+)-([def]+))"}, {pattern: " is "}, {pattern: "\\1"}, {pattern: ", not "}, {pattern: "([^xyz]+)"}, {pattern: ", "}, {pattern: "\\2"}, {pattern: ", or "}, {pattern: "\\3"}},
+	},
+	{
+		description: "VComplex capture group",
+		pattern:     "('(cat) and \\2') is the same as \\1",
+		expected:    []subpattern{{pattern: "('(cat) and \\2')"}, {pattern: " is the same as "}, {pattern: "\\1"}},
+	},
+	{
+		description: "Complex capture group",
+		pattern:     "(\\w+ ca+t)",
+		expected:    []subpattern{{pattern: "(\\w+ ca+t)"}},
+	},
+	{
+		description: "Complex capture group",
+		pattern:     "(\\w+ \\d+) is doing \\1 times",
+		expected:    []subpattern{{pattern: "(\\w+ \\d+)"}, {pattern: " is doing "}, {pattern: "\\1"}, {pattern: " times"}},
+	},
+	{
+		description: "Complex capture group",
+		pattern:     "([abcd]+) is \\1, not [^xyz]+",
+		expected:    []subpattern{{pattern: "([abcd]+)"}, {pattern: " is "}, {pattern: "\\1"}, {pattern: ", not "}, {pattern: "[^xyz]", sign: plusSign}},
+	},
+	{
+		description: "Complex capture group",
+		pattern:     "(\\w\\w\\w\\w \\d\\d\\d) is doing \\1 times",
+		expected:    []subpattern{{pattern: "(\\w\\w\\w\\w \\d\\d\\d)"}, {pattern: " is doing "}, {pattern: "\\1"}, {pattern: " times"}},
+	},
+	{
+		description: "Negative character group",
+		pattern:     "^[^xyz]",
+		expected:    []subpattern{{pattern: "[^xyz]"}},
+	},
+	{
+		description: "single backreference",
+		pattern:     "(\\w+) and \\1",
+		expected:    []subpattern{{pattern: "(\\w+)"}, {pattern: " and "}, {pattern: "\\1"}},
+	},
+	{
+		description: "single backreference",
+		pattern:     "(cat) and \\1",
+		expected:    []subpattern{{pattern: "(cat)"}, {pattern: " and "}, {pattern: "\\1"}},
+	},
+	{
+		description: "one alternation",
+		pattern:     "a (cat|dog)",
+		expected:    []subpattern{{pattern: "a "}, {pattern: "(cat|dog)", sign: alternationSign}},
+	},
+	{
+		description: "one alternation",
+		pattern:     "(a|c)",
+		expected:    []subpattern{{pattern: "(a|c)", sign: alternationSign}},
+	},
+	{
+		description: "one quantifier",
+		pattern:     "a+bc",
+		expected:    []subpattern{{pattern: "a", sign: plusSign}, {pattern: "bc"}},
+	},
+	{
+		description: "one quantifier",
+		pattern:     "ab+cd",
+		expected:    []subpattern{{pattern: "a"}, {pattern: "b", sign: plusSign}, {pattern: "cd"}},
+	},
+	{
+		description: "one quantifier",
+		pattern:     "ab?cd",
+		expected:    []subpattern{{pattern: "a"}, {pattern: "b", sign: optionalSign}, {pattern: "cd"}},
+	},
+}
+
+var testGrep = []struct {
+	description string
+	pattern     string
+	line        string
+	expected    bool
+}{
+	{
+		description: "complex nested backreference",
+		line:        "cat and fish, cat with fish, cat and fish",
+		pattern:     "((c.t|d.g) and (f..h|b..d)), \\2 with \\3, \\1",
+		expected:    true,
+	},
+	{
+		description: "complex nested backreference",
+		line:        "abc-def is abc-def, not xyz, abc, or def",
+		pattern:     "(([abc]+)-([def]+)) is \\1, not ([^xyz]+), \\2, or \\3",
+		expected:    false,
+	},
+	{
+		description: "complex nested backreference",
+		line:        "abc-def is abc-def, not efg, abc, or def",
+		pattern:     "(([abc]+)-([def]+)) is \\1, not ([^xyz]+), \\2, or \\3",
+		expected:    true,
+	},
+	{
+		description: "nested backreference",
+		line:        "grep 101 is doing grep 101 times, and again grep 101 times",
+		pattern:     "((\\w\\w\\w\\w) (\\d\\d\\d)) is doing \\2 \\3 times, and again \\1 times",
+		expected:    true,
+	},
+	{
+		description: "nested backreference",
+		line:        "'cat and cat' is the same as 'cat and cat'",
+		pattern:     "('(cat) and \\2') is the same as \\1",
+		expected:    true,
+	},
+	{
+		description: "complex single backreference",
+		line:        "bugs here and bugs there",
+		pattern:     "(b..s|c..e) here and \\1 there",
+		expected:    true,
+	},
+	{
+		description: "complex single backreference",
+		line:        "this starts and ends with this",
+		pattern:     "^(\\w+) starts and ends with \\1$",
+		expected:    true,
+	},
+	{
+		description: "single backreference",
+		line:        "abcd is abcd, not efg",
+		pattern:     "([abcd]+) is \\1, not [^xyz]+",
+		expected:    true,
+	},
+	{
+		description: "single backreference",
+		line:        "grep 101 is doing grep 101 times",
+		pattern:     "(\\w\\w\\w\\w \\d\\d\\d) is doing \\1 times",
+		expected:    true,
+	},
+	{
+		description: "single backreference",
+		pattern:     "(cat) and \\1",
+		line:        "cat and cat",
+		expected:    true,
+	},
+	{
+		description: "single backreference",
+		pattern:     "(cat) and \\1",
+		line:        "cat and dog",
+		expected:    false,
+	},
+	{
+		description: "alternation",
+		pattern:     "a (cat|dog)",
+		line:        "a dog",
+		expected:    true,
+	},
+	{
+		description: "alternation",
+		pattern:     "a (cat|dog)",
+		line:        "a cat",
+		expected:    true,
+	},
+	{
+		description: "alternation",
+		pattern:     "a (cat|dog)",
+		line:        "a rat",
+		expected:    false,
+	},
+	{
+		description: "wildcard character",
+		pattern:     "c.t",
+		line:        "car",
+		expected:    false,
+	},
+	{
+		description: "wildcard character",
+		pattern:     "c.t",
+		line:        "cut",
+		expected:    true,
+	},
+	{
+		description: "optional character",
+		pattern:     "ca?t",
+		line:        "act",
+		expected:    true,
+	},
+	{
+		description: "match one or more characters",
+		pattern:     "ca+t",
+		line:        "caaats",
+		expected:    true,
+	},
+	{
+		description: "single character match",
+		pattern:     "d",
+		line:        "dog",
+		expected:    true,
+	},
+	{
+		description: "Combining character classes",
+		pattern:     "\\d+ apple",
+		line:        "sally has 33 apples",
+		expected:    true,
+	},
+	{
+		description: "Combining character classes",
+		pattern:     "\\d apple",
+		line:        "sally has 3 apples",
+		expected:    true,
+	},
+	{
+		description: "Combining character classes",
+		pattern:     "\\d \\w\\w\\ws",
+		line:        "sally has 3 dogs",
+		expected:    true,
+	},
+	{
+		description: "Combining character classes",
+		pattern:     "\\d \\w\\w\\ws",
+		line:        "sally has 1 dog",
+		expected:    false,
+	},
+	{
+		description: "Negative character group",
+		pattern:     "^[^xyz]",
+		line:        "apple",
+		expected:    true,
+	},
+	{
+		description: "Negative character group",
+		pattern:     "^[^xyz]",
+		line:        "xpple",
+		expected:    false,
+	},
+	{
+		description: "Negative character group",
+		pattern:     "[^xyz]",
+		line:        "xxe",
+		expected:    true,
+	},
+	{
+		description: "Negative character group",
+		pattern:     "[^xyz]",
+		line:        "apple",
+		expected:    true,
+	},
+	{
+		description: "Negative character group",
+		pattern:     "[^anb]",
+		line:        "banana",
+		expected:    false,
+	},
+	{
+		description: "Positive Character Group",
+		pattern:     "[abcd]",
+		line:        "a",
+		expected:    true,
+	},
+	{
+		description: "start of string anchor",
+		pattern:     "^log",
+		line:        "log",
+		expected:    true,
+	},
+	{
+		description: "start of string anchor",
+		pattern:     "^log",
+		line:        "slog",
+		expected:    false,
+	},
+	{
+		description: "end of string anchor",
+		pattern:     "log$",
+		line:        "log",
+		expected:    true,
+	},
+	{
+		description: "end of string anchor",
+		pattern:     "log$",
+		line:        "logs",
+		expected:    false,
+	},
+	{
+		description: "match quantifiers",
+		pattern:     "\\d+c",
+		line:        "33c",
+		expected:    true,
+	},
+}
+
+func getPatternStringFromPatterns(patterns []subpattern) []string {
+	out := make([]string, len(patterns))
+	for x, p := range patterns {
+		out[x] = p.pattern
+	}
+	return out
+}
+
+func TestSimplePatternExtraction(t *testing.T) {
+	for _, tp := range testSimplePatternExtraction {
+		t.Run(tp.description, func(t *testing.T) {
+			gh := newGrepHandler([]byte("balls"), tp.pattern)
+			if err := gh.extractPatterns(); err != nil {
+				t.Fatalf("error returned: %s", err)
+			}
+			patterns := getPatternStringFromPatterns(gh.patterns)
+			if !reflect.DeepEqual(patterns, tp.expected) {
+				t.Fatalf("no matching pattern: got %v expected: %v", gh.patterns, tp.expected)
+			}
+		})
+	}
+}
+
+func TestPatternWithQuantifierExtraction(t *testing.T) {
+	for _, tp := range testPatternWithQuantifierExtraction {
+		t.Run(tp.description, func(t *testing.T) {
+			gh := newGrepHandler([]byte("balls"), tp.pattern)
+			if err := gh.extractPatterns(); err != nil {
+				t.Fatalf("error returned: %s", err)
+			} else if !reflect.DeepEqual(gh.patterns, tp.expected) {
+				t.Fatalf("no matching pattern: got %v expected: %v", gh.patterns, tp.expected)
+			}
+		})
+	}
+}
+
+func TestGreps(t *testing.T) {
+	for _, tp := range testGrep {
+		t.Run(tp.description, func(t *testing.T) {
+			gh := newGrepHandler([]byte(tp.line), tp.pattern)
+			if err := gh.extractPatterns(); err != nil {
+				t.Fatalf("error returned: %s", err)
+			} else {
+				loc, err := gh.matchPatterns()
+				if err != nil {
+					t.Fatalf("error returned: %s", err)
+				} else if actual := loc != nil; actual != tp.expected {
+					t.Fatalf("failed to match %s with pattern: %s", tp.line, tp.pattern)
+				}
+			}
+		})
+	}
+}
+
+var testFindAllMatches = []struct {
+	description string
+	pattern     string
+	line        string
+	expected    [][]int
+}{
+	{
+		description: "every occurrence",
+		pattern:     "cat",
+		line:        "cat and cat",
+		expected:    [][]int{{0, 3}, {8, 11}},
+	},
+	{
+		description: "quantifier",
+		pattern:     "\\d+",
+		line:        "foo 12 bar 345",
+		expected:    [][]int{{4, 6}, {11, 14}},
+	},
+	{
+		description: "start of string anchor",
+		pattern:     "^a",
+		line:        "aaa",
+		expected:    [][]int{{0, 1}},
+	},
+	{
+		description: "end of string anchor",
+		pattern:     "a$",
+		line:        "aaa",
+		expected:    [][]int{{2, 3}},
+	},
+	{
+		description: "character group",
+		pattern:     "[ab]c",
+		line:        "ac bc cc",
+		expected:    [][]int{{0, 2}, {3, 5}},
+	},
+	{
+		description: "empty matches",
+		pattern:     "b?",
+		line:        "abb",
+		expected:    [][]int{{0, 0}, {1, 2}, {2, 3}},
+	},
+	{
+		description: "backreference",
+		pattern:     "(\\w+) and \\1",
+		line:        "cat and cat, dog and dog",
+		expected:    [][]int{{0, 11}, {13, 24}},
+	},
+	{
+		description: "quantified literal bracket",
+		pattern:     "x]+",
+		line:        "ax]]",
+		expected:    [][]int{{1, 4}},
+	},
+	{
+		description: "leading literal bracket",
+		pattern:     "]?b",
+		line:        "b ]b",
+		expected:    [][]int{{0, 1}, {2, 4}},
+	},
+	{
+		description: "backslash and digit in a character group",
+		pattern:     "[^\\2]b",
+		line:        "2b \\b ab",
+		expected:    [][]int{{6, 8}},
+	},
+	{
+		description: "quantified backreference",
+		pattern:     "(a)\\1+x",
+		line:        "aaax ax",
+		expected:    [][]int{{0, 4}},
+	},
+	{
+		description: "backreference to an empty group",
+		pattern:     "(?<n>)\\1+b\\1",
+		line:        "ab",
+		expected:    [][]int{{1, 2}},
+	},
+	{
+		description: "backreference to an outer group in a group",
+		pattern:     "(a)(b\\1)c",
+		line:        "abac",
+		expected:    [][]int{{0, 4}},
+	},
+	{
+		description: "no match",
+		pattern:     "z",
+		line:        "cat",
+		expected:    [][]int{},
+	},
+}
+
+func TestFindAllMatches(t *testing.T) {
+	for _, tp := range testFindAllMatches {
+		t.Run(tp.description, func(t *testing.T) {
+			gh := newGrepHandler([]byte(tp.line), tp.pattern)
+			if err := gh.extractPatterns(); err != nil {
+				t.Fatalf("error returned: %s", err)
+			}
+			actual, err := gh.findAllMatches()
+			if err != nil {
+				t.Fatalf("error returned: %s", err)
+			} else if !reflect.DeepEqual(actual, tp.expected) {
+				t.Fatalf("wrong matches for %s with pattern %s: got %v expected: %v", tp.line, tp.pattern, actual, tp.expected)
+			}
+		})
+	}
+}
+
+func TestMatchedPatternOffsets(t *testing.T) {
+	gh := newGrepHandler([]byte("sally has 12 apples"), "\\d+ (apple)")
+	if err := gh.extractPatterns(); err != nil {
+		t.Fatalf("error returned: %s", err)
+	}
+	loc, _ := gh.matchPatterns()
+	if !reflect.DeepEqual(loc, []int{10, 18}) {
+		t.Fatalf("wrong match position: got %v expected: [10 18]", loc)
+	}
+	expected := [][]int{{10, 12}, {12, 13}, {13, 18}}
+	var actual [][]int
+	for _, mp := range gh.matched_patterns {
+		actual = append(actual, []int{mp.start, mp.end})
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("wrong subpattern offsets: got %v expected: %v", actual, expected)
+	}
+}
+
+var testSyntaxErrors = []struct {
+	description string
+	pattern     string
+	code        ErrorCode
+	offset      int
+}{
+	{description: "leading quantifier", pattern: "+a", code: ErrMissingRepeatArgument, offset: 0},
+	{description: "quantifier after an alternation", pattern: "(a|?b)", code: ErrMissingRepeatArgument, offset: 3},
+	{description: "quantifier after an anchor", pattern: "^?a", code: ErrMissingRepeatArgument, offset: 1},
+	{description: "nested quantifiers", pattern: "ab+?", code: ErrInvalidRepeatOperator, offset: 2},
+	{description: "missing closing parenthesis", pattern: "a(b(c)", code: ErrMissingParen, offset: 1},
+	{description: "unexpected closing parenthesis", pattern: "ab)c", code: ErrUnexpectedParen, offset: 2},
+	{description: "missing closing bracket", pattern: "a[bc", code: ErrMissingBracket, offset: 1},
+	{description: "trailing backslash", pattern: "ab\\", code: ErrTrailingBackslash, offset: 2},
+	{description: "backreference without group", pattern: "a \\1", code: ErrInvalidBackReference, offset: 2},
+	{description: "backreference ahead of its group", pattern: "(a) \\2 (b)", code: ErrInvalidBackReference, offset: 4},
+	{description: "unknown group flag", pattern: "a(?x)", code: ErrInvalidNamedCapture, offset: 1},
+	{description: "empty group name", pattern: "(?<>a)", code: ErrInvalidNamedCapture, offset: 0},
+	{description: "duplicate group name", pattern: "(?<x>a)(?P<x>b)", code: ErrDuplicateNamedCapture, offset: 7},
+	{description: "flags after the start", pattern: "a(?s)b", code: ErrMisplacedFlags, offset: 1},
+	{description: "unknown flag", pattern: "(?i)a", code: ErrInvalidNamedCapture, offset: 0},
+	{description: "repeated group", pattern: "x(ab)+", code: ErrUnsupportedGroupRepeat, offset: 5},
+	{description: "optional alternation", pattern: "(a|b)?", code: ErrUnsupportedGroupRepeat, offset: 5},
+	{description: "repeated named group", pattern: "(?<n>\\d)+", code: ErrUnsupportedGroupRepeat, offset: 8},
+}
+
+func TestSyntaxErrors(t *testing.T) {
+	for _, tp := range testSyntaxErrors {
+		t.Run(tp.description, func(t *testing.T) {
+			gh := newGrepHandler([]byte("balls"), tp.pattern)
+			err := gh.extractPatterns()
+			var syntax_err *SyntaxError
+			if !errors.As(err, &syntax_err) {
+				t.Fatalf("expected a syntax error, got %v", err)
+			} else if syntax_err.Code != tp.code || syntax_err.Offset != tp.offset {
+				t.Fatalf("got %q at offset %d expected: %q at offset %d", syntax_err.Code, syntax_err.Offset, tp.code, tp.offset)
+			}
+		})
+	}
+}
+
+func TestSyntaxErrorDiagram(t *testing.T) {
+	err := newSyntaxError(ErrMissingParen, "é(ab", 2)
+	expected := "é(ab\n ^"
+	if err.Diagram() != expected {
+		t.Fatalf("wrong diagram: got %q expected: %q", err.Diagram(), expected)
+	}
+}
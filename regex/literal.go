@@ -0,0 +1,148 @@
+package regex
+
+import (
+	"bytes"
+	"strings"
+)
+
+// the largest set of literals worth looking for, with more of them a search for each one costs more than it saves
+const maxLiteralSet = 8
+
+// the literals that every match of a program contains, found by going through its subpatterns once compiled.
+// They are looked for with bytes.Index before running the engine, to skip the positions where no match can start
+type prefilter struct {
+	prefix   [][]byte //a match starts with one of these, nil if unknown
+	required [][]byte //a match contains one of these, nil if unknown
+}
+
+// find the literals required by the subpatterns of a program
+func analyzeLiterals(prog *program) *prefilter {
+	pf := &prefilter{}
+	if len(prog.patterns) > 0 {
+		pf.prefix, _ = prog.literalsAt(0)
+	}
+	for x := range prog.patterns {
+		if _, required := prog.literalsAt(x); betterLiterals(required, pf.required) {
+			pf.required = required
+		}
+	}
+	if betterLiterals(pf.prefix, pf.required) {
+		pf.required = pf.prefix
+	}
+	return pf
+}
+
+// the literals of the subpattern at index x: the match of the subpattern starts with one of the prefixes
+// and contains one of the required literals. nil when nothing is known, for example for \d+ or an optional subpattern
+func (prog *program) literalsAt(x int) (prefix, required [][]byte) {
+	pat := prog.patterns[x]
+	if pat.sign == optionalSign {
+		return nil, nil
+	}
+	switch {
+	case isDigitMatch(pat.pattern), isAlphaNumericMatch(pat.pattern), isBackReference(pat.pattern), isCharacterGroupMatch(pat.pattern):
+		return nil, nil
+	case isCaptureGroupMatch(pat.pattern):
+		if alternatives := prog.alternatives[x]; alternatives != nil {
+			return alternationLiterals(alternatives)
+		} else if group := prog.groups[x]; group != nil && pat.sign != alternationSign {
+			return group.prefilter.prefix, group.prefilter.required
+		}
+		return nil, nil
+	}
+	//a literal, where the wildcard matches any byte
+	return literalPrefix(pat.pattern), literalRun(pat.pattern)
+}
+
+// the literals of the alternatives of a capture group, one of them starts the match of the group
+func alternationLiterals(alternatives []string) (prefix, required [][]byte) {
+	if len(alternatives) > maxLiteralSet {
+		return nil, nil
+	}
+	for _, alternative := range alternatives {
+		p, r := literalPrefix(alternative), literalRun(alternative)
+		if p == nil || r == nil {
+			return nil, nil
+		}
+		prefix, required = append(prefix, p[0]), append(required, r[0])
+	}
+	return prefix, required
+}
+
+// the part of a literal before its first wildcard, nil if it starts with a wildcard
+func literalPrefix(literal string) [][]byte {
+	if end := strings.IndexByte(literal, '.'); end >= 0 {
+		literal = literal[:end]
+	}
+	if literal == "" {
+		return nil
+	}
+	return [][]byte{[]byte(literal)}
+}
+
+// the longest part of a literal without a wildcard, nil if it is only wildcards
+func literalRun(literal string) [][]byte {
+	longest := ""
+	for _, run := range strings.Split(literal, ".") {
+		if len(run) > len(longest) {
+			longest = run
+		}
+	}
+	if longest == "" {
+		return nil
+	}
+	return [][]byte{[]byte(longest)}
+}
+
+// check if a set of literals is more selective than another one: its shortest literal is longer,
+// or as long but there are fewer literals to look for
+func betterLiterals(a, b [][]byte) bool {
+	if a == nil {
+		return false
+	} else if b == nil {
+		return true
+	}
+	if shortestLiteral(a) != shortestLiteral(b) {
+		return shortestLiteral(a) > shortestLiteral(b)
+	}
+	return len(a) < len(b)
+}
+
+func shortestLiteral(literals [][]byte) int {
+	shortest := len(literals[0])
+	for _, literal := range literals[1:] {
+		shortest = min(shortest, len(literal))
+	}
+	return shortest
+}
+
+// the offset of the first required literal at or after from, a match can't start after it. -1 if the rest of the line
+// contains none of the required literals, so that nothing can match; len(line) when no literal is required.
+// The search goes forward from the offset, so that looking for the matches one after the other reads the line once
+func (pf *prefilter) nextRequired(line []byte, from int) int {
+	if pf.required == nil {
+		return len(line)
+	}
+	next := -1
+	for _, literal := range pf.required {
+		if x := bytes.Index(line[from:], literal); x >= 0 && (next < 0 || from+x < next) {
+			next = from + x
+		}
+	}
+	return next
+}
+
+// the offset of the next position at or after from where a match can start, -1 if there is none.
+// From itself when no prefix is known
+func (pf *prefilter) nextStart(line []byte, from int) int {
+	if pf.prefix == nil {
+		return from
+	}
+	next := -1
+	for _, literal := range pf.prefix {
+		if x := bytes.Index(line[from:], literal); x >= 0 && (next < 0 || from+x < next) {
+			next = from + x
+		}
+	}
+	return next
+}
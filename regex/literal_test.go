@@ -0,0 +1,98 @@
+package regex
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAnalyzeLiterals(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		prefix   []string
+		required []string
+	}{
+		{"ERROR \\d+", []string{"ERROR "}, []string{"ERROR "}},
+		{"(foo|bar)baz", []string{"foo", "bar"}, []string{"baz"}},
+		{"\\d+ (apple|pear)s", nil, []string{"apple", "pear"}},
+		{"a.cdef", []string{"a"}, []string{"cdef"}},
+		{"x?yz", nil, []string{"yz"}},
+		{"(a+b) c", []string{"a"}, []string{" c"}},
+		{"\\w+", nil, nil},
+		{"[abc]+", nil, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.pattern, func(t *testing.T) {
+			pf := compile(test.pattern).prefilter
+			if actual := literalStrings(pf.prefix); !reflect.DeepEqual(actual, test.prefix) {
+				t.Fatalf("prefix: got %q expected: %q", actual, test.prefix)
+			}
+			if actual := literalStrings(pf.required); !reflect.DeepEqual(actual, test.required) {
+				t.Fatalf("required: got %q expected: %q", actual, test.required)
+			}
+		})
+	}
+}
+
+// the prefilter only skips the offsets where no match can start, the matches are the same without it
+func TestPrefilterKeepsMatches(t *testing.T) {
+	patterns := []string{"ERROR \\d+", "(foo|bar)baz", "a.c", "ab+c", "\\d+ (cat|dog)s?", "^foo", "bar$", "(\\w+) and \\1"}
+	lines := []string{"ERROR 12 ERROR x ERROR 3", "foobaz barbaz fobaz", "abc a c axc", "abbbc ac abc", "3 cats 4 dog 5 cow", "foo foo", "bar bar", "cat and cat, dog and cow"}
+	for _, pattern := range patterns {
+		prog := compile(pattern)
+		unfiltered := *prog
+		unfiltered.prefilter = &prefilter{}
+		for _, line := range lines {
+			expected, err := newProgramHandler([]byte(line), &unfiltered).findAllMatches()
+			if err != nil {
+				t.Fatalf("error returned: %s", err)
+			}
+			actual, err := newProgramHandler([]byte(line), prog).findAllMatches()
+			if err != nil {
+				t.Fatalf("error returned: %s", err)
+			} else if !reflect.DeepEqual(actual, expected) {
+				t.Fatalf("%q in %q: got %v expected: %v", pattern, line, actual, expected)
+			}
+		}
+	}
+}
+
+// the required literals are looked for from the cursor on, looking for them through the whole buffer for each match
+// made the search quadratic: many matches followed by a long tail without any took minutes
+func TestPrefilterIsLinear(t *testing.T) {
+	data := append(bytes.Repeat([]byte("ERROR 12 foo\n"), 20000), bytes.Repeat([]byte("plain line of text\n"), 200000)...)
+	re := MustCompile("ERROR \\d+", Options{})
+	done := make(chan [2]int, 1)
+	go func() {
+		//each match of the buffer, then each line with a match the way --mmap looks for them
+		matches, lines := len(re.FindAllIndex(data, -1)), 0
+		for pos := 0; ; lines++ {
+			loc := re.FindLineIndex(data[pos:])
+			if loc == nil {
+				break
+			}
+			pos += loc[1] + 1
+		}
+		done <- [2]int{matches, lines}
+	}()
+	select {
+	case actual := <-done:
+		if actual != [2]int{20000, 20000} {
+			t.Fatalf("got %d matches and %d lines expected: 20000 of each", actual[0], actual[1])
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("the search of many matches followed by a long tail without any is too slow")
+	}
+}
+
+func literalStrings(literals [][]byte) []string {
+	if literals == nil {
+		return nil
+	}
+	strs := make([]string, len(literals))
+	for x, literal := range literals {
+		strs[x] = string(literal)
+	}
+	return strs
+}
@@ -0,0 +1,76 @@
+package regex
+
+import "strings"
+
+// a compiled pattern, once compiled it is never modified so it can be shared by many handlers and goroutines
+type program struct {
+	pattern                string       //the raw pattern
+	patterns               []subpattern //the slice of our subpatterns
+	match_start, match_end bool         //start and end string anchors
+	match_word             bool         //the match has to be a whole word
+	groups                 []*program   //for each subpattern, the program of its capture group, nil if it isn't a group
+	alternatives           [][]string   //for each subpattern, the alternatives of its capture group, nil if it isn't an alternation
+	prefilter              *prefilter   //the literals every match contains
+	flags                               //the flags written at the start of the pattern
+}
+
+// compile the raw pattern into a program, the pattern is expected to be valid.
+// capture groups are compiled here rather than each time they are matched
+func compile(pattern string) *program {
+	//the flags apply to the whole pattern, capture groups included
+	f, length := parseFlags(pattern)
+	prog := &program{pattern: pattern[length:]}
+	prog.splitPatterns()
+	prog.groups = make([]*program, len(prog.patterns))
+	prog.alternatives = make([][]string, len(prog.patterns))
+	for x, pat := range prog.patterns {
+		if !isCaptureGroupMatch(pat.pattern) {
+			continue
+		}
+		//remove the parenthesis
+		group_pattern := strings.TrimSuffix(strings.TrimPrefix(pat.pattern, "("), ")")
+		//and the name of the group, the names are only needed to expand templates
+		if _, length, ok := groupName(group_pattern); ok {
+			group_pattern = group_pattern[length:]
+		}
+		if group_pattern == "" {
+			continue
+		} else if pat.sign == alternationSign && group_pattern[0] != '(' {
+			prog.alternatives[x] = strings.Split(group_pattern, "|")
+		} else {
+			prog.groups[x] = prog.compileGroup(x, group_pattern)
+		}
+	}
+	prog.prefilter = analyzeLiterals(prog)
+	prog.setFlags(f)
+	return prog
+}
+
+// compile the capture group of the subpattern at index x
+func (prog *program) compileGroup(x int, group_pattern string) *program {
+	group := compile(group_pattern)
+	//the backreferences of the group keep the numbers of the whole pattern, the groups share their offsets
+	for y, pat := range group.patterns {
+		if isCharacterGroupMatch(pat.pattern) {
+			if pat.pattern[1] == '^' {
+				//is there a next pattern in the group?
+				if y+1 < len(group.patterns) {
+					group.patterns[y].nextbyte = group.patterns[y+1].pattern[0]
+				} else if x+1 < len(prog.patterns) {
+					var fb rune
+					for _, b := range prog.patterns[x+1].pattern {
+						//!!!!!!!!!!! THIS NEEDS IMPROVEMENTS
+						if b == '[' || b == '(' {
+							continue
+						} else {
+							fb = b
+							break
+						}
+					}
+					group.patterns[y].nextbyte = byte(fb)
+				}
+			}
+		}
+	}
+	return group
+}
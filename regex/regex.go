@@ -0,0 +1,291 @@
+// Package regex implements the regular expressions of mygrep: literals, the . wildcard, the \d and \w
+// character classes, positive and negative character groups, the + and ? quantifiers, the ^ and $ anchors,
+// capture groups, named or not, with alternations and backreferences. A quantifier can't follow a capture group.
+//
+// The pattern can start with flags: with (?s) the wildcard matches a newline, which it doesn't by default,
+// and with (?m) ^ and $ match at the start and end of every line rather than only at the start and end of the input.
+// Both are written (?sm).
+//
+// Offsets are byte offsets, and the matches of the Find methods follow the conventions of Go's regexp package.
+package regex
+
+import (
+	"strings"
+	"sync"
+)
+
+// Options change the way a compiled pattern matches
+type Options struct {
+	Word bool //a match has to be a whole word, like grep -w
+	Line bool //a match has to be the whole input, like grep -x
+	//the wildcard matches a newline as with the s flag, for inputs where a newline is an ordinary byte
+	DotNewline bool
+}
+
+// Regexp is a compiled pattern. It is never modified once compiled and is safe for concurrent use by many goroutines
+type Regexp struct {
+	expr        string
+	prog        *program
+	group_names []string  //the name of each capture group, empty for a group without a name
+	line_safe   bool      //a match can't span a newline and doesn't depend on the start or end of the line
+	handlers    sync.Pool //the handlers holding the state of a match, reused from one search to the next
+}
+
+// Compile checks the syntax of the pattern and compiles it.
+// An invalid pattern is reported with a *SyntaxError
+func Compile(pattern string, opts Options) (*Regexp, error) {
+	group_names, err := checkSyntax(pattern)
+	if err != nil {
+		return nil, err
+	}
+	prog := compile(pattern)
+	//a whole line match is a match anchored at both ends
+	prog.match_start = prog.match_start || opts.Line
+	prog.match_end = prog.match_end || opts.Line
+	prog.match_word = opts.Word
+	prog.setFlags(flags{dot_newline: prog.dot_newline || opts.DotNewline, multi_line: prog.multi_line})
+	//the wildcard and negated groups can match a newline, the anchors need the line boundaries
+	line_safe := !strings.ContainsAny(pattern, ".^$\n") && !opts.Line
+	re := &Regexp{expr: pattern, prog: prog, group_names: group_names, line_safe: line_safe}
+	re.handlers.New = func() any {
+		return newProgramHandler(nil, prog)
+	}
+	return re, nil
+}
+
+// MustCompile is like Compile but panics if the pattern is invalid
+func MustCompile(pattern string, opts Options) *Regexp {
+	re, err := Compile(pattern, opts)
+	if err != nil {
+		panic(err)
+	}
+	return re
+}
+
+// String returns the source pattern
+func (re *Regexp) String() string {
+	return re.expr
+}
+
+// NumSubexp returns the number of capture groups
+func (re *Regexp) NumSubexp() int {
+	return len(re.group_names)
+}
+
+// SubexpNames returns the names of the capture groups, written (?<name>...) or (?P<name>...).
+// The first element is the name of the whole match and is always empty, as is the name of a group without a name
+func (re *Regexp) SubexpNames() []string {
+	return append([]string{""}, re.group_names...)
+}
+
+// SubexpIndex returns the index of the capture group with the given name, -1 if there is no such group
+func (re *Regexp) SubexpIndex(name string) int {
+	if name == "" {
+		return -1
+	}
+	for x, group_name := range re.group_names {
+		if group_name == name {
+			return x + 1
+		}
+	}
+	return -1
+}
+
+// get a handler matching b, the handler keeps the state of its match so every search gets its own
+func (re *Regexp) handler(b []byte) *grepHandler {
+	gh := re.handlers.Get().(*grepHandler)
+	gh.line = b
+	return gh
+}
+
+// give back a handler once the search is over
+func (re *Regexp) release(gh *grepHandler) {
+	gh.line = nil
+	re.handlers.Put(gh)
+}
+
+// the offsets of the match followed by the offsets of each capture group, -1 for a group that didn't match
+func (re *Regexp) submatchIndex(gh *grepHandler, start, end int) []int {
+	loc := make([]int, 2+2*len(re.group_names))
+	loc[0], loc[1] = start, end
+	for x := range re.group_names {
+		if x < len(gh.backreferences) {
+			loc[2+2*x], loc[3+2*x] = gh.backreferences[x][0], gh.backreferences[x][1]
+		} else {
+			loc[2+2*x], loc[3+2*x] = -1, -1
+		}
+	}
+	return loc
+}
+
+// the submatch offsets of the leftmost match, nil if there is no match
+func (re *Regexp) find(b []byte) []int {
+	return re.findFrom(b, 0)
+}
+
+// the submatch offsets of the leftmost match starting at or after the from offset, nil if there is no match.
+// the syntax of the pattern was checked by Compile, so an error of the matcher is reported as no match
+func (re *Regexp) findFrom(b []byte, from int) []int {
+	if from < 0 || from > len(b) {
+		return nil
+	}
+	gh := re.handler(b)
+	defer re.release(gh)
+	start, end, ok, err := gh.findMatch(from)
+	if err != nil || !ok {
+		return nil
+	}
+	return re.submatchIndex(gh, start, end)
+}
+
+// the submatch offsets of at most n matches, every match if n < 0. nil if there is no match
+func (re *Regexp) findAll(b []byte, n int) [][]int {
+	var matches [][]int
+	gh := re.handler(b)
+	defer re.release(gh)
+	err := gh.forEachMatch(n, func(start, end int) {
+		matches = append(matches, re.submatchIndex(gh, start, end))
+	})
+	if err != nil {
+		return nil
+	}
+	return matches
+}
+
+// Match reports whether b contains a match of the pattern
+func (re *Regexp) Match(b []byte) bool {
+	return re.find(b) != nil
+}
+
+// MatchString reports whether s contains a match of the pattern
+func (re *Regexp) MatchString(s string) bool {
+	return re.Match([]byte(s))
+}
+
+// Find returns the leftmost match in b, nil if there is no match
+func (re *Regexp) Find(b []byte) []byte {
+	loc := re.find(b)
+	if loc == nil {
+		return nil
+	}
+	return b[loc[0]:loc[1]:loc[1]]
+}
+
+// FindString returns the leftmost match in s, an empty string if there is no match
+func (re *Regexp) FindString(s string) string {
+	loc := re.find([]byte(s))
+	if loc == nil {
+		return ""
+	}
+	return s[loc[0]:loc[1]]
+}
+
+// FindIndex returns the [start, end] offsets of the leftmost match in b, nil if there is no match
+func (re *Regexp) FindIndex(b []byte) []int {
+	loc := re.find(b)
+	if loc == nil {
+		return nil
+	}
+	return loc[0:2]
+}
+
+// FindStringIndex returns the [start, end] offsets of the leftmost match in s, nil if there is no match
+func (re *Regexp) FindStringIndex(s string) []int {
+	return re.FindIndex([]byte(s))
+}
+
+// FindSubmatch returns the leftmost match in b followed by the text of each capture group,
+// nil for a group that didn't match. Returns nil if there is no match
+func (re *Regexp) FindSubmatch(b []byte) [][]byte {
+	loc := re.find(b)
+	if loc == nil {
+		return nil
+	}
+	submatches := make([][]byte, len(loc)/2)
+	for x := range submatches {
+		if loc[2*x] >= 0 {
+			submatches[x] = b[loc[2*x]:loc[2*x+1]:loc[2*x+1]]
+		}
+	}
+	return submatches
+}
+
+// FindStringSubmatch returns the leftmost match in s followed by the text of each capture group,
+// an empty string for a group that didn't match. Returns nil if there is no match
+func (re *Regexp) FindStringSubmatch(s string) []string {
+	loc := re.find([]byte(s))
+	if loc == nil {
+		return nil
+	}
+	submatches := make([]string, len(loc)/2)
+	for x := range submatches {
+		if loc[2*x] >= 0 {
+			submatches[x] = s[loc[2*x]:loc[2*x+1]]
+		}
+	}
+	return submatches
+}
+
+// FindSubmatchIndex returns the offsets of the leftmost match in b followed by the offsets of each capture group,
+// -1 for a group that didn't match. Returns nil if there is no match
+func (re *Regexp) FindSubmatchIndex(b []byte) []int {
+	return re.find(b)
+}
+
+// FindStringSubmatchIndex is like FindSubmatchIndex for a string
+func (re *Regexp) FindStringSubmatchIndex(s string) []int {
+	return re.find([]byte(s))
+}
+
+// FindSubmatchIndexFrom is like FindSubmatchIndex for the leftmost match starting at or after the from offset.
+// Unlike a search of b[from:], the text before from still counts for the ^ anchor and the word boundaries.
+// Returns nil if there is no match
+func (re *Regexp) FindSubmatchIndexFrom(b []byte, from int) []int {
+	return re.findFrom(b, from)
+}
+
+// FindAll returns at most n successive non-overlapping matches in b, every match if n < 0.
+// Returns nil if there is no match
+func (re *Regexp) FindAll(b []byte, n int) [][]byte {
+	var matches [][]byte
+	for _, loc := range re.findAll(b, n) {
+		matches = append(matches, b[loc[0]:loc[1]:loc[1]])
+	}
+	return matches
+}
+
+// FindAllString returns at most n successive non-overlapping matches in s, every match if n < 0.
+// Returns nil if there is no match
+func (re *Regexp) FindAllString(s string, n int) []string {
+	var matches []string
+	for _, loc := range re.findAll([]byte(s), n) {
+		matches = append(matches, s[loc[0]:loc[1]])
+	}
+	return matches
+}
+
+// FindAllIndex returns the [start, end] offsets of at most n successive non-overlapping matches in b,
+// every match if n < 0. Returns nil if there is no match
+func (re *Regexp) FindAllIndex(b []byte, n int) [][]int {
+	var matches [][]int
+	for _, loc := range re.findAll(b, n) {
+		matches = append(matches, loc[0:2])
+	}
+	return matches
+}
+
+// FindAllStringIndex is like FindAllIndex for a string
+func (re *Regexp) FindAllStringIndex(s string, n int) [][]int {
+	return re.FindAllIndex([]byte(s), n)
+}
+
+// FindAllSubmatchIndex returns the submatch offsets, as returned by FindSubmatchIndex, of at most n successive
+// non-overlapping matches in b, every match if n < 0. Returns nil if there is no match
+func (re *Regexp) FindAllSubmatchIndex(b []byte, n int) [][]int {
+	return re.findAll(b, n)
+}
+
+// FindAllStringSubmatchIndex is like FindAllSubmatchIndex for a string
+func (re *Regexp) FindAllStringSubmatchIndex(s string, n int) [][]int {
+	return re.findAll([]byte(s), n)
+}
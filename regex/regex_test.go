@@ -0,0 +1,183 @@
+package regex
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCompile(t *testing.T) {
+	re, err := Compile("(\\w+) and \\1", Options{})
+	if err != nil {
+		t.Fatalf("error returned: %s", err)
+	} else if re.String() != "(\\w+) and \\1" || re.NumSubexp() != 1 {
+		t.Fatalf("wrong compiled pattern: %q with %d groups", re.String(), re.NumSubexp())
+	}
+	if _, err := Compile("(ab", Options{}); err == nil {
+		t.Fatal("expected a syntax error")
+	}
+}
+
+func TestFind(t *testing.T) {
+	re := MustCompile("\\d+ (apple|pear)", Options{})
+	line := "sally has 12 apples and 3 pears"
+	if !re.MatchString(line) || re.MatchString("no fruit") {
+		t.Fatal("wrong match result")
+	}
+	if actual := re.FindString(line); actual != "12 apple" {
+		t.Fatalf("FindString: got %q expected: %q", actual, "12 apple")
+	}
+	if actual := re.Find([]byte(line)); string(actual) != "12 apple" {
+		t.Fatalf("Find: got %q expected: %q", actual, "12 apple")
+	}
+	if actual := re.FindIndex([]byte(line)); !reflect.DeepEqual(actual, []int{10, 18}) {
+		t.Fatalf("FindIndex: got %v expected: %v", actual, []int{10, 18})
+	}
+	if actual := re.FindStringSubmatch(line); !reflect.DeepEqual(actual, []string{"12 apple", "apple"}) {
+		t.Fatalf("FindStringSubmatch: got %q", actual)
+	}
+	if actual := re.FindAllString(line, -1); !reflect.DeepEqual(actual, []string{"12 apple", "3 pear"}) {
+		t.Fatalf("FindAllString: got %q", actual)
+	}
+	if actual := re.FindAllString(line, 1); !reflect.DeepEqual(actual, []string{"12 apple"}) {
+		t.Fatalf("FindAllString with a limit: got %q", actual)
+	}
+	if actual := re.FindAll([]byte("nothing"), -1); actual != nil {
+		t.Fatalf("FindAll without match: got %q expected: nil", actual)
+	}
+	if actual := re.FindSubmatchIndexFrom([]byte(line), 12); !reflect.DeepEqual(actual, []int{24, 30, 26, 30}) {
+		t.Fatalf("FindSubmatchIndexFrom: got %v", actual)
+	}
+	//the text before the offset still counts for the anchors and the word boundaries
+	if actual := MustCompile("^a", Options{}).FindSubmatchIndexFrom([]byte("aa"), 1); actual != nil {
+		t.Fatalf("FindSubmatchIndexFrom with an anchor: got %v expected: nil", actual)
+	}
+	if actual := MustCompile("cat", Options{Word: true}).FindSubmatchIndexFrom([]byte("bobcat cat"), 3); !reflect.DeepEqual(actual, []int{7, 10}) {
+		t.Fatalf("FindSubmatchIndexFrom with whole words: got %v", actual)
+	}
+}
+
+func TestFindAllSubmatchIndex(t *testing.T) {
+	re := MustCompile("((c.t|d.g) and (f..h|b..d))", Options{})
+	line := "cat and fish, dog and bird"
+	expected := [][]int{{0, 12, 0, 12, 0, 3, 8, 12}, {14, 26, 14, 26, 14, 17, 22, 26}}
+	if actual := re.FindAllStringSubmatchIndex(line, -1); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("got %v expected: %v", actual, expected)
+	}
+	if actual := re.FindSubmatch([]byte(line)); string(actual[2]) != "cat" || string(actual[3]) != "fish" {
+		t.Fatalf("FindSubmatch: got %q", actual)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	word := MustCompile("cat", Options{Word: true})
+	if actual := word.FindAllStringIndex("bobcat cat cats", -1); !reflect.DeepEqual(actual, [][]int{{7, 10}}) {
+		t.Fatalf("whole word: got %v", actual)
+	}
+	line := MustCompile("\\d+", Options{Line: true})
+	if !line.MatchString("123") || line.MatchString("123a") {
+		t.Fatal("whole line: wrong match result")
+	}
+	//the first alternative matches, but only the second one leaves the rest of the line to the end anchor
+	alternation := MustCompile("(a|ab)", Options{Line: true})
+	if actual := alternation.FindStringSubmatchIndex("ab"); !reflect.DeepEqual(actual, []int{0, 2, 0, 2}) {
+		t.Fatalf("whole line alternation: got %v", actual)
+	}
+	if actual := MustCompile("(a|ab)", Options{Word: true}).FindStringIndex("ab a"); !reflect.DeepEqual(actual, []int{0, 2}) {
+		t.Fatalf("whole word alternation: got %v", actual)
+	}
+	if !MustCompile("(a|ab)c", Options{}).MatchString("abc") {
+		t.Fatal("alternation followed by a literal: wrong match result")
+	}
+	dot := MustCompile("a.b", Options{DotNewline: true})
+	if !dot.MatchString("a\nb") {
+		t.Fatal("dot newline: wrong match result")
+	}
+}
+
+// patterns made of random pieces of syntax, every one has to be either rejected by Compile or matched without a panic
+func TestRandomPatterns(t *testing.T) {
+	pieces := []string{"a", "b", " ", ".", "(", ")", "|", "[", "[^", "]", "^", "$", "+", "?", "\\", "\\1", "\\2", "\\d", "\\w", "(?<n>", "(?s)"}
+	lines := []string{"", "ab", "aab b", "a]b", "x\nab ba|", "\\2 ]]"}
+	r := rand.New(rand.NewSource(1))
+	for x := 0; x < 20000; x++ {
+		var pattern strings.Builder
+		for n := r.Intn(8) + 1; n > 0; n-- {
+			pattern.WriteString(pieces[r.Intn(len(pieces))])
+		}
+		func() {
+			defer func() {
+				if err := recover(); err != nil {
+					t.Fatalf("%q: %v", pattern.String(), err)
+				}
+			}()
+			re, err := Compile(pattern.String(), Options{})
+			if err != nil {
+				return
+			}
+			for _, line := range lines {
+				re.FindAllSubmatchIndex([]byte(line), -1)
+			}
+			Explain(pattern.String())
+		}()
+	}
+}
+
+func TestFlags(t *testing.T) {
+	input := "cat\ndog\nbird"
+	tests := []struct {
+		pattern  string
+		expected []string
+	}{
+		{"t.d", nil},
+		{"(?s)t.d", []string{"t\nd"}},
+		{"(?s)(t.|g.)", []string{"t\n", "g\n"}},
+		{"^\\w+", []string{"cat"}},
+		{"(?m)^\\w+", []string{"cat", "dog", "bird"}},
+		{"\\w+$", []string{"bird"}},
+		{"(?m)\\w+$", []string{"cat", "dog", "bird"}},
+		{"(?sm)^d.+", []string{"dog\nbird"}},
+	}
+	for _, test := range tests {
+		re := MustCompile(test.pattern, Options{})
+		if actual := re.FindAllString(input, -1); !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("%q: got %q expected: %q", test.pattern, actual, test.expected)
+		}
+	}
+}
+
+func TestConcurrentUse(t *testing.T) {
+	re := MustCompile("((\\w\\w\\w\\w) (\\d\\d\\d)) is doing \\2 \\3 times", Options{})
+	lines := []string{"grep 101 is doing grep 101 times", "sed 42 is doing nothing", "find 123 is doing find 123 times"}
+	expected := make([][]int, len(lines))
+	for x, line := range lines {
+		expected[x] = re.FindStringSubmatchIndex(line)
+	}
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				x := i % len(lines)
+				if actual := re.FindStringSubmatchIndex(lines[x]); !reflect.DeepEqual(actual, expected[x]) {
+					t.Errorf("got %v expected: %v", actual, expected[x])
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestCompiledProgramIsNotModified(t *testing.T) {
+	re := MustCompile("(a (cat|dog)) and ([^xyz]+), \\1", Options{})
+	before := fmt.Sprintf("%+v", re.prog.patterns)
+	re.FindAllString("a cat and abc, a cat a dog and x", -1)
+	if after := fmt.Sprintf("%+v", re.prog.patterns); after != before {
+		t.Fatalf("the program was modified by a search: %s became %s", before, after)
+	}
+}
@@ -0,0 +1,115 @@
+package regex
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// Expand appends template to dst with its variables replaced by the capture groups of match, a match in src
+// as returned by FindSubmatchIndex. In the template:
+//
+//	$1 or ${1}        is the text of the first capture group
+//	$name or ${name}  is the text of the capture group named name
+//	\1                is the text of the first capture group, like a backreference
+//	$$ and \\         are a literal $ and a literal \
+//
+// A name is the longest sequence of letters, digits and underscores: $1x is the group named 1x, not ${1}x.
+// A group that doesn't exist or didn't match is replaced by an empty string, and a malformed $ is copied as is
+func (re *Regexp) Expand(dst, template, src []byte, match []int) []byte {
+	for len(template) > 0 {
+		x := bytes.IndexAny(template, "$\\")
+		if x < 0 {
+			break
+		}
+		dst = append(dst, template[:x]...)
+		template = template[x:]
+		if len(template) < 2 {
+			break
+		}
+		if template[0] == '\\' {
+			//only \digits and \\ are special, any other escape is kept as is
+			end := 1
+			for end < len(template) && isDigit(template[end]) {
+				end++
+			}
+			if end > 1 {
+				index, _ := strconv.Atoi(string(template[1:end]))
+				dst = re.appendGroup(dst, src, match, index)
+			} else if template[1] == '\\' {
+				dst, end = append(dst, '\\'), 2
+			} else {
+				dst, end = append(dst, template[:2]...), 2
+			}
+			template = template[end:]
+			continue
+		}
+		if template[1] == '$' {
+			dst = append(dst, '$')
+			template = template[2:]
+			continue
+		}
+		name, length, ok := templateName(template)
+		if !ok {
+			//malformed, the $ is copied as is
+			dst = append(dst, '$')
+			template = template[1:]
+			continue
+		}
+		template = template[length:]
+		if index, err := strconv.Atoi(name); err == nil {
+			dst = re.appendGroup(dst, src, match, index)
+		} else {
+			dst = re.appendGroup(dst, src, match, re.SubexpIndex(name))
+		}
+	}
+	return append(dst, template...)
+}
+
+// the name of the $name or ${name} variable at the start of the template along with the length of the variable
+func templateName(template []byte) (name string, length int, ok bool) {
+	braces := len(template) > 1 && template[1] == '{'
+	start := 1
+	if braces {
+		start = 2
+	}
+	end := start
+	for end < len(template) && isAlphaNumeric(template[end]) {
+		end++
+	}
+	if end == start {
+		return "", 0, false
+	}
+	if braces {
+		if end >= len(template) || template[end] != '}' {
+			return "", 0, false
+		}
+		return string(template[start:end]), end + 1, true
+	}
+	return string(template[start:end]), end, true
+}
+
+// append the text of the capture group at index, nothing if the group doesn't exist or didn't match
+func (re *Regexp) appendGroup(dst, src []byte, match []int, index int) []byte {
+	if index < 0 || 2*index+1 >= len(match) || match[2*index] < 0 {
+		return dst
+	}
+	return append(dst, src[match[2*index]:match[2*index+1]]...)
+}
+
+// ReplaceAll returns a copy of src with every match of the pattern replaced by template,
+// the variables of the template are expanded as with Expand
+func (re *Regexp) ReplaceAll(src, template []byte) []byte {
+	dst := make([]byte, 0, len(src))
+	last := 0
+	for _, match := range re.findAll(src, -1) {
+		dst = append(dst, src[last:match[0]]...)
+		dst = re.Expand(dst, template, src, match)
+		last = match[1]
+	}
+	return append(dst, src[last:]...)
+}
+
+// ReplaceAllString is like ReplaceAll for strings
+func (re *Regexp) ReplaceAllString(src, template string) string {
+	return string(re.ReplaceAll([]byte(src), []byte(template)))
+}
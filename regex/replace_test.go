@@ -0,0 +1,49 @@
+package regex
+
+import (
+	"testing"
+)
+
+func TestReplaceAll(t *testing.T) {
+	tests := []struct {
+		name     string
+		pattern  string
+		template string
+		input    string
+		expected string
+	}{
+		{"literal", "cat", "dog", "cat and cats", "dog and dogs"},
+		{"numbered group", "(\\w+)@(\\w+)", "$2 at $1", "bob@home, ann@work", "home at bob, work at ann"},
+		{"braced group", "(\\d+)", "${1}0", "5 apples", "50 apples"},
+		{"name swallows the digits", "(\\d+)", "$1x", "5 apples", " apples"},
+		{"backslash group", "(\\w+) (\\w+)", "\\2 \\1", "hello world", "world hello"},
+		{"named group", "(?<user>\\w+)@(?P<host>\\w+)", "${host}:$user", "bob@home", "home:bob"},
+		{"unknown name", "(\\w+)", "[$name]", "hi", "[]"},
+		{"literal dollar and backslash", "\\d+", "$$\\\\", "costs 5", "costs $\\"},
+		{"malformed variable", "\\d+", "${1", "5", "${1"},
+		{"whole match", "\\d+", "<$0>", "a1b22", "a<1>b<22>"},
+		{"empty matches", "x?", "-", "abc", "-a-b-c-"},
+		{"no match", "z", "y", "abc", "abc"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			re := MustCompile(test.pattern, Options{})
+			if actual := re.ReplaceAllString(test.input, test.template); actual != test.expected {
+				t.Fatalf("got %q expected: %q", actual, test.expected)
+			}
+		})
+	}
+}
+
+func TestSubexpNames(t *testing.T) {
+	re := MustCompile("(?<user>\\w+)@(\\w+)", Options{})
+	if names := re.SubexpNames(); len(names) != 3 || names[1] != "user" || names[2] != "" {
+		t.Fatalf("got %q", names)
+	}
+	if re.SubexpIndex("user") != 1 || re.SubexpIndex("host") != -1 {
+		t.Fatal("wrong group index")
+	}
+	if actual := re.FindStringSubmatch("bob@home"); len(actual) != 3 || actual[1] != "bob" || actual[2] != "home" {
+		t.Fatalf("named group match: got %q", actual)
+	}
+}
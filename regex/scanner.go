@@ -0,0 +1,172 @@
+package regex
+
+import (
+	"bytes"
+	"io"
+)
+
+// the size of the chunks read by a LineScanner
+const defaultChunkSize = 64 * 1024
+
+// LineScanner reads an input in chunks of a fixed size and yields its lines, a line split across two chunks
+// is carried over to the next one. Lines end with a newline, or with the terminator set by SetTerminator.
+// The memory used doesn't depend on the size of the input but on the length of its longest line,
+// since a line has to be whole to be matched.
+//
+//	ls := regex.NewLineScanner(r)
+//	for ls.Scan() {
+//		line := ls.Line()
+//	}
+//	if err := ls.Err(); err != nil {
+type LineScanner struct {
+	r          io.Reader
+	buf        []byte
+	start, end int //the part of buf that was read but not scanned yet
+	line       []byte
+	terminator byte  //the byte ending the lines
+	newline    bool  //the current line ended with the terminator
+	number     int   //1-based number of the current line
+	offset     int64 //byte offset of the current line in the input
+	next       int64 //byte offset of the next line
+	err        error
+	eof        bool
+}
+
+// NewLineScanner returns a LineScanner reading r
+func NewLineScanner(r io.Reader) *LineScanner {
+	return NewLineScannerSize(r, defaultChunkSize)
+}
+
+// NewLineScannerSize returns a LineScanner reading r in chunks of size bytes
+func NewLineScannerSize(r io.Reader, size int) *LineScanner {
+	return &LineScanner{r: r, buf: make([]byte, max(size, 1)), terminator: '\n'}
+}
+
+// SetTerminator sets the byte ending the lines, a newline by default. For example NUL-terminated records
+// are read with a NUL terminator. It must be called before the first call to Scan
+func (ls *LineScanner) SetTerminator(terminator byte) {
+	ls.terminator = terminator
+}
+
+// Scan advances to the next line, which is then available through Line.
+// Returns false at the end of the input or on an error, reported by Err
+func (ls *LineScanner) Scan() bool {
+	for {
+		if x := bytes.IndexByte(ls.buf[ls.start:ls.end], ls.terminator); x >= 0 {
+			ls.setLine(ls.buf[ls.start:ls.start+x], true)
+			return true
+		}
+		if ls.eof {
+			if ls.start == ls.end {
+				return false
+			}
+			//the last line doesn't end with the terminator
+			ls.setLine(ls.buf[ls.start:ls.end], false)
+			return true
+		}
+		ls.fill()
+	}
+}
+
+// make line the current line and skip it
+func (ls *LineScanner) setLine(line []byte, newline bool) {
+	ls.line, ls.newline = line, newline
+	length := len(line)
+	if newline {
+		length++
+	}
+	ls.start += length
+	ls.offset = ls.next
+	ls.next += int64(length)
+	ls.number++
+}
+
+// read the next chunk of the input after the part that isn't scanned yet
+func (ls *LineScanner) fill() {
+	//move the start of the partial line to the front of the buffer, the buffer only grows for a line longer than it
+	if ls.start > 0 {
+		ls.end = copy(ls.buf, ls.buf[ls.start:ls.end])
+		ls.start = 0
+	}
+	if ls.end == len(ls.buf) {
+		ls.buf = append(ls.buf, make([]byte, len(ls.buf))...)
+	}
+	n, err := ls.r.Read(ls.buf[ls.end:])
+	ls.end += n
+	if err != nil {
+		ls.eof = true
+		if err != io.EOF {
+			ls.err = err
+		}
+	}
+}
+
+// Line returns the current line without its terminator. It is only valid until the next call to Scan
+func (ls *LineScanner) Line() []byte {
+	return ls.line
+}
+
+// Newline reports whether the current line ended with the terminator, a newline by default.
+// Only the last line of the input can lack it
+func (ls *LineScanner) Newline() bool {
+	return ls.newline
+}
+
+// LineNumber returns the 1-based number of the current line
+func (ls *LineScanner) LineNumber() int {
+	return ls.number
+}
+
+// Offset returns the byte offset of the current line in the input
+func (ls *LineScanner) Offset() int64 {
+	return ls.offset
+}
+
+// Err returns the error that stopped the LineScanner, nil at the end of the input
+func (ls *LineScanner) Err() error {
+	return ls.err
+}
+
+// MatchReader reports whether the input read from r contains a match of the pattern.
+// Like grep, the input is matched line by line and is read in chunks rather than loaded whole.
+// Returns the first read error
+func (re *Regexp) MatchReader(r io.Reader) (bool, error) {
+	ls := NewLineScanner(r)
+	for ls.Scan() {
+		if re.Match(ls.Line()) {
+			return true, nil
+		}
+	}
+	return false, ls.Err()
+}
+
+// FindLineIndex returns the [start, end] offsets of the first line of b containing a match, without its newline,
+// nil if no line has a match. When a match can't span lines the whole of b is searched at once and only the line
+// around the first match is located, otherwise each line is matched in turn
+func (re *Regexp) FindLineIndex(b []byte) []int {
+	if !re.line_safe {
+		for start := 0; start < len(b); {
+			end := bytes.IndexByte(b[start:], '\n')
+			if end < 0 {
+				end = len(b)
+			} else {
+				end += start
+			}
+			if re.Match(b[start:end]) {
+				return []int{start, end}
+			}
+			start = end + 1
+		}
+		return nil
+	}
+	loc := re.find(b)
+	if loc == nil {
+		return nil
+	}
+	start := bytes.LastIndexByte(b[:loc[0]], '\n') + 1
+	end := bytes.IndexByte(b[loc[1]:], '\n')
+	if end < 0 {
+		return []int{start, len(b)}
+	}
+	return []int{start, loc[1] + end}
+}
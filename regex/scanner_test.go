@@ -0,0 +1,87 @@
+package regex
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestLineScanner(t *testing.T) {
+	input := "short\na line longer than a chunk\n\nlast"
+	expected := []string{"short", "a line longer than a chunk", "", "last"}
+	offsets := []int64{0, 6, 33, 34}
+	for _, size := range []int{1, 4, 64} {
+		ls := NewLineScannerSize(iotest.HalfReader(strings.NewReader(input)), size)
+		var lines []string
+		for ls.Scan() {
+			if ls.Offset() != offsets[len(lines)] {
+				t.Fatalf("chunk size %d: line %d at offset %d expected: %d", size, len(lines), ls.Offset(), offsets[len(lines)])
+			}
+			if ls.Newline() != (len(lines) < 3) {
+				t.Fatalf("chunk size %d: wrong newline for line %d", size, len(lines))
+			}
+			lines = append(lines, string(ls.Line()))
+		}
+		if ls.Err() != nil {
+			t.Fatalf("error returned: %s", ls.Err())
+		} else if !reflect.DeepEqual(lines, expected) {
+			t.Fatalf("chunk size %d: got %q expected: %q", size, lines, expected)
+		}
+		if len(ls.buf) > 32 && size < 32 {
+			t.Fatalf("chunk size %d: the buffer grew to %d bytes", size, len(ls.buf))
+		}
+	}
+}
+
+func TestMatchReader(t *testing.T) {
+	re := MustCompile("^\\d+$", Options{})
+	if ok, err := re.MatchReader(strings.NewReader("a1\n22\nb")); !ok || err != nil {
+		t.Fatalf("got %v %v expected: true <nil>", ok, err)
+	}
+	if ok, err := re.MatchReader(strings.NewReader("a1\nb2")); ok || err != nil {
+		t.Fatalf("got %v %v expected: false <nil>", ok, err)
+	}
+	failure := errors.New("failure")
+	if ok, err := re.MatchReader(iotest.ErrReader(failure)); ok || err != failure {
+		t.Fatalf("got %v %v expected: false %v", ok, err, failure)
+	}
+}
+
+func TestFindLineIndex(t *testing.T) {
+	input := []byte("no match\ncat 12\nlast cat")
+	tests := []struct {
+		pattern  string
+		opts     Options
+		expected []int
+	}{
+		{"\\d+", Options{}, []int{9, 15}},
+		{"^last", Options{}, []int{16, 24}},
+		{"cat$", Options{}, []int{16, 24}},
+		{"c.t", Options{}, []int{9, 15}},
+		{"cat", Options{Line: true}, nil},
+		{"cat", Options{Word: true}, []int{9, 15}},
+		{"dog", Options{}, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.pattern, func(t *testing.T) {
+			re := MustCompile(test.pattern, test.opts)
+			if actual := re.FindLineIndex(input); !reflect.DeepEqual(actual, test.expected) {
+				t.Fatalf("got %v expected: %v", actual, test.expected)
+			}
+		})
+	}
+}
+
+func TestLineScannerTerminator(t *testing.T) {
+	ls := NewLineScannerSize(strings.NewReader("a\nb\x00c\x00"), 2)
+	ls.SetTerminator(0)
+	var lines []string
+	for ls.Scan() {
+		lines = append(lines, string(ls.Line()))
+	}
+	if expected := []string{"a\nb", "c"}; !reflect.DeepEqual(lines, expected) {
+		t.Fatalf("got %q expected: %q", lines, expected)
+	}
+}
@@ -0,0 +1,127 @@
+package regex
+
+import (
+	"bytes"
+	"io"
+)
+
+// Split slices s into the substrings separated by the matches of the pattern, like Go's regexp.Split.
+// n is the maximum number of substrings, the last one being the unsplit remainder; every substring if n < 0
+// and nil if n == 0. An empty match at the start of s doesn't produce an empty first substring,
+// so splitting "abc" with an empty pattern gives a, b and c
+func (re *Regexp) Split(s string, n int) []string {
+	if n != 0 && len(re.expr) > 0 && len(s) == 0 {
+		return []string{""}
+	}
+	return SplitMatches(s, re.findAll([]byte(s), n), n)
+}
+
+// SplitMatches slices s around matches, the successive non-overlapping matches of s as returned by FindAllIndex
+// or FindAllSubmatchIndex, the way Split slices s around the matches of its pattern
+func SplitMatches(s string, matches [][]int, n int) []string {
+	if n == 0 {
+		return nil
+	}
+	fields := make([]string, 0)
+	start, end := 0, 0
+	for _, match := range matches {
+		if n > 0 && len(fields) == n-1 {
+			break
+		}
+		end = match[0]
+		if match[1] != 0 {
+			fields = append(fields, s[start:end])
+		}
+		start = match[1]
+	}
+	if end != len(s) {
+		fields = append(fields, s[start:])
+	}
+	return fields
+}
+
+// Token is a segment of the input of a Tokenizer
+type Token struct {
+	Text  []byte
+	Match bool //the segment is a match of the pattern, otherwise it is the text between two matches
+}
+
+// Tokenizer reads an input and yields the matches of a pattern along with the text between them, in order.
+// Like grep, the input is matched line by line: a match never spans a newline and the ^ and $ anchors
+// match at the start and end of each line. The text between two matches is yielded as a single token
+// even when it spans many lines, and is never empty; a match can be empty.
+//
+//	tk := re.Tokenizer(r)
+//	for tk.Next() {
+//		token := tk.Token()
+//	}
+//	if err := tk.Err(); err != nil {
+type Tokenizer struct {
+	re      *Regexp
+	ls      *LineScanner
+	text    []byte  //the text read since the last match
+	queue   []Token //the tokens found but not yielded yet
+	current Token
+	err     error
+	done    bool //the whole input was read
+}
+
+// Tokenizer returns a Tokenizer reading r
+func (re *Regexp) Tokenizer(r io.Reader) *Tokenizer {
+	return &Tokenizer{re: re, ls: NewLineScanner(r)}
+}
+
+// Next advances to the next token, which is then available through Token.
+// Returns false at the end of the input or on an error, reported by Err
+func (tk *Tokenizer) Next() bool {
+	for len(tk.queue) == 0 {
+		if tk.done {
+			return false
+		}
+		tk.readLine()
+	}
+	tk.current = tk.queue[0]
+	tk.queue = tk.queue[1:]
+	return true
+}
+
+// Token returns the token found by the last call to Next
+func (tk *Tokenizer) Token() Token {
+	return tk.current
+}
+
+// Err returns the error that stopped the Tokenizer, nil at the end of the input
+func (tk *Tokenizer) Err() error {
+	return tk.err
+}
+
+// read a line of the input and queue the tokens it completes
+func (tk *Tokenizer) readLine() {
+	if !tk.ls.Scan() {
+		tk.done = true
+		tk.err = tk.ls.Err()
+		tk.flushText()
+		return
+	}
+	line := tk.ls.Line()
+	last := 0
+	for _, match := range tk.re.findAll(line, -1) {
+		tk.text = append(tk.text, line[last:match[0]]...)
+		tk.flushText()
+		//the line is only valid until the next one is read
+		tk.queue = append(tk.queue, Token{Text: bytes.Clone(line[match[0]:match[1]]), Match: true})
+		last = match[1]
+	}
+	tk.text = append(tk.text, line[last:]...)
+	if tk.ls.Newline() {
+		tk.text = append(tk.text, '\n')
+	}
+}
+
+// queue the text read since the last match, if any
+func (tk *Tokenizer) flushText() {
+	if len(tk.text) > 0 {
+		tk.queue = append(tk.queue, Token{Text: tk.text})
+		tk.text = nil
+	}
+}
@@ -0,0 +1,55 @@
+package regex
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		input    string
+		n        int
+		expected []string
+	}{
+		{",", "a,b,c", -1, []string{"a", "b", "c"}},
+		{",", "a,b,c", 2, []string{"a", "b,c"}},
+		{",", "a,b,c", 0, nil},
+		{",", ",a,", -1, []string{"", "a", ""}},
+		{"\\d+", "a12b3c", -1, []string{"a", "b", "c"}},
+		{"x?", "abc", -1, []string{"a", "b", "c"}},
+		{"a?", "abc", -1, []string{"", "b", "c"}},
+		{"z", "abc", -1, []string{"abc"}},
+		{"z", "", -1, []string{""}},
+	}
+	for _, test := range tests {
+		t.Run(test.pattern+" "+test.input, func(t *testing.T) {
+			re := MustCompile(test.pattern, Options{})
+			if actual := re.Split(test.input, test.n); !reflect.DeepEqual(actual, test.expected) {
+				t.Fatalf("got %q expected: %q", actual, test.expected)
+			}
+		})
+	}
+}
+
+func TestTokenizer(t *testing.T) {
+	re := MustCompile("^\\d+", Options{})
+	tk := re.Tokenizer(strings.NewReader("12 apples\nno fruit\n3 pears"))
+	var actual []string
+	for tk.Next() {
+		token := tk.Token()
+		if token.Match {
+			actual = append(actual, "["+string(token.Text)+"]")
+		} else {
+			actual = append(actual, string(token.Text))
+		}
+	}
+	if tk.Err() != nil {
+		t.Fatalf("error returned: %s", tk.Err())
+	}
+	expected := []string{"[12]", " apples\nno fruit\n", "[3]", " pears"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("got %q expected: %q", actual, expected)
+	}
+}
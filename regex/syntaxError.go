@@ -0,0 +1,165 @@
+package regex
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrorCode describes the kind of a syntax error in a pattern
+type ErrorCode string
+
+const (
+	ErrMissingRepeatArgument  ErrorCode = "missing argument to repetition operator"
+	ErrInvalidRepeatOperator  ErrorCode = "invalid nested repetition operator"
+	ErrMissingParen           ErrorCode = "missing closing )"
+	ErrUnexpectedParen        ErrorCode = "unexpected )"
+	ErrMissingBracket         ErrorCode = "missing closing ]"
+	ErrTrailingBackslash      ErrorCode = "trailing backslash at end of expression"
+	ErrInvalidBackReference   ErrorCode = "invalid back reference"
+	ErrInvalidNamedCapture    ErrorCode = "invalid named capture"
+	ErrDuplicateNamedCapture  ErrorCode = "duplicate capture group name"
+	ErrMisplacedFlags         ErrorCode = "flags are only allowed at the start of the pattern"
+	ErrUnsupportedGroupRepeat ErrorCode = "repetition of a capture group is not supported"
+)
+
+// SyntaxError is returned by Compile for an invalid pattern
+type SyntaxError struct {
+	Code    ErrorCode
+	Pattern string
+	Offset  int //byte offset of the offending part of the pattern
+}
+
+func newSyntaxError(code ErrorCode, pattern string, offset int) *SyntaxError {
+	return &SyntaxError{Code: code, Pattern: pattern, Offset: offset}
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("error parsing regexp: %s at offset %d: `%s`", e.Code, e.Offset, e.Pattern)
+}
+
+// Diagram returns the pattern with a caret under the offending part
+//
+//	(abc
+//	^
+func (e *SyntaxError) Diagram() string {
+	//count the characters rather than the bytes so that the caret is aligned with multibyte characters
+	padding := utf8.RuneCountInString(e.Pattern[:e.Offset])
+	return e.Pattern + "\n" + strings.Repeat(" ", padding) + "^"
+}
+
+// check that the pattern can be split into subpatterns, this is where invalid patterns are reported
+// instead of failing in the middle of a match. Returns the names of the capture groups of the pattern,
+// an empty name for a group without a name
+func checkSyntax(pattern string) ([]string, error) {
+	open_groups := make([]int, 0) //offsets of the parenthesis that are not closed yet
+	group_names := make([]string, 0)
+	can_repeat := false //there is something before a quantifier to repeat
+	previous_quantifier := false
+	group_closed := false //the previous character closed a capture group
+	//the flags come first, what follows them is checked as a pattern on its own
+	_, start := parseFlags(pattern)
+	for x := start; x < len(pattern); x++ {
+		quantifier, closed := false, false
+		switch pattern[x] {
+		case '\\':
+			if x+1 >= len(pattern) {
+				return nil, newSyntaxError(ErrTrailingBackslash, pattern, x)
+			}
+			start := x
+			x++
+			if pattern[x] >= '1' && pattern[x] <= '9' {
+				end := x
+				for end < len(pattern) && isDigit(pattern[end]) {
+					end++
+				}
+				//a backreference can only refer to a group that was opened before it
+				index, err := strconv.Atoi(pattern[x:end])
+				if err != nil || index > len(group_names) {
+					return nil, newSyntaxError(ErrInvalidBackReference, pattern, start)
+				}
+				x = end - 1
+			}
+			can_repeat = true
+		case '[':
+			end := strings.IndexByte(pattern[x+1:], ']')
+			if end < 0 {
+				return nil, newSyntaxError(ErrMissingBracket, pattern, x)
+			}
+			x += end + 1
+			can_repeat = true
+		case '(':
+			if _, length := parseFlags(pattern[x:]); length > 0 {
+				return nil, newSyntaxError(ErrMisplacedFlags, pattern, x)
+			}
+			open_groups = append(open_groups, x)
+			name, length, ok := groupName(pattern[x+1:])
+			if !ok {
+				return nil, newSyntaxError(ErrInvalidNamedCapture, pattern, x)
+			} else if name != "" && slices.Contains(group_names, name) {
+				return nil, newSyntaxError(ErrDuplicateNamedCapture, pattern, x)
+			}
+			group_names = append(group_names, name)
+			x += length
+			can_repeat = false
+		case ')':
+			if len(open_groups) == 0 {
+				return nil, newSyntaxError(ErrUnexpectedParen, pattern, x)
+			}
+			open_groups = open_groups[:len(open_groups)-1]
+			can_repeat, closed = true, true
+		case '|':
+			can_repeat = false
+		case '+', '?':
+			if previous_quantifier {
+				return nil, newSyntaxError(ErrInvalidRepeatOperator, pattern, x-1)
+			} else if !can_repeat {
+				return nil, newSyntaxError(ErrMissingRepeatArgument, pattern, x)
+			} else if group_closed {
+				//the matcher only repeats a single character, a class or a backreference
+				return nil, newSyntaxError(ErrUnsupportedGroupRepeat, pattern, x)
+			}
+			quantifier = true
+		case '^':
+			//the start of string anchor can't be repeated, anywhere else ^ is a literal
+			can_repeat = x > start
+		default:
+			can_repeat = true
+		}
+		previous_quantifier, group_closed = quantifier, closed
+	}
+	if len(open_groups) > 0 {
+		return nil, newSyntaxError(ErrMissingParen, pattern, open_groups[len(open_groups)-1])
+	}
+	return group_names, nil
+}
+
+// the name of a capture group written (?<name>...) or (?P<name>...), s is the pattern after the parenthesis.
+// Returns the name along with the length of its syntax, ok is false if the syntax is invalid
+func groupName(s string) (name string, length int, ok bool) {
+	if !strings.HasPrefix(s, "?") {
+		return "", 0, true
+	}
+	prefix := "?<"
+	if strings.HasPrefix(s, "?P<") {
+		prefix = "?P<"
+	} else if !strings.HasPrefix(s, prefix) {
+		return "", 0, false
+	}
+	end := strings.IndexByte(s, '>')
+	if end < 0 {
+		return "", 0, false
+	}
+	name = s[len(prefix):end]
+	if name == "" {
+		return "", 0, false
+	}
+	for x := 0; x < len(name); x++ {
+		if !isAlphaNumeric(name[x]) {
+			return "", 0, false
+		}
+	}
+	return name, end + 1, true
+}
@@ -1,6 +1,9 @@
-package main
+package regex
 
 func isBackReference(pattern string) bool {
+	if len(pattern) < 2 {
+		return false
+	}
 	for i, c := range pattern {
 		if i == 0 {
 			if c != '\\' {
@@ -26,11 +29,11 @@ func isAlphaOrDigitMatch(pattern string) bool {
 }
 
 func isCharacterGroupMatch(pattern string) bool {
-	return pattern[0] == '[' && pattern[len(pattern)-1] == ']'
+	return len(pattern) >= 2 && pattern[0] == '[' && pattern[len(pattern)-1] == ']'
 }
 
 func isCaptureGroupMatch(pattern string) bool {
-	return pattern[0] == '(' && pattern[len(pattern)-1] == ')'
+	return len(pattern) >= 2 && pattern[0] == '(' && pattern[len(pattern)-1] == ')'
 }
 
 func getCharacterGroupPattern(pattern string, isPositive bool) []byte {
@@ -52,16 +55,6 @@ func getCharacterGroupPattern(pattern string, isPositive bool) []byte {
 	return group
 }
 
-// check for the presence of a wildcard character in a pattern
-func patternHasAWildcard(pattern string) bool {
-	for _, b := range pattern {
-		if b == '.' {
-			return true
-		}
-	}
-	return false
-}
-
 func contains(slice []byte, target byte) bool {
 	for _, b := range slice {
 		if b == target {
@@ -94,3 +87,24 @@ func isAnyCaseLetter(b byte) bool {
 func isUnderScore(b byte) bool {
 	return b == '_'
 }
+
+// check if the pattern byte matches the line byte, a wildcard matches any byte but a newline unless dot_newline
+func matchesByte(pattern, b byte, dot_newline bool) bool {
+	if pattern == '.' {
+		return b != '\n' || dot_newline
+	}
+	return pattern == b
+}
+
+// check if the line starts with the pattern, taking wildcards into account
+func hasPrefixWithWildcard(line []byte, pattern string, dot_newline bool) bool {
+	if len(line) < len(pattern) {
+		return false
+	}
+	for x := 0; x < len(pattern); x++ {
+		if !matchesByte(pattern[x], line[x], dot_newline) {
+			return false
+		}
+	}
+	return true
+}